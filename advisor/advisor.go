@@ -0,0 +1,116 @@
+package advisor
+
+import (
+	"sort"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// Finding describes risky setting detected on node
+type Finding struct {
+	RuleID      string
+	Severity    string // one of alerting severities
+	ClusterName string
+	GroupName   string
+	HostName    string
+	Directive   string
+	Value       interface{}
+	Message     string
+}
+
+// NodeStatus holds statuses of single node checked by rules
+type NodeStatus struct {
+	Opcache observer.NodeOpcacheStatus
+	Apcu    observer.NodeApcuStatus
+}
+
+// Rule checks node status and returns finding when setting is risky
+type Rule struct {
+	ID string
+	// Rule evaluated only on nodes of production clusters
+	ProductionOnly bool
+	Check          func(node NodeStatus) *Finding
+}
+
+// Advisor evaluates enabled rules over configuration of nodes
+type Advisor struct {
+	rules              []Rule
+	productionClusters map[string]bool
+}
+
+// NewAdvisor builds advisor with all known rules except disabled ones
+func NewAdvisor(disabledRules map[string]bool, productionClusters map[string]bool) *Advisor {
+	advisor := &Advisor{
+		productionClusters: productionClusters,
+	}
+
+	for _, rule := range Rules() {
+		if disabledRules[rule.ID] {
+			continue
+		}
+
+		advisor.rules = append(advisor.rules, rule)
+	}
+
+	return advisor
+}
+
+// Advise checks all nodes with pulled statistics and returns findings ordered by location
+func (a *Advisor) Advise(
+	opcacheStatuses observer.ClustersOpcacheStatuses,
+	apcuStatuses observer.ClustersApcuStatuses,
+) []Finding {
+	findings := []Finding{}
+
+	for clusterName, groups := range opcacheStatuses {
+		for groupName, hosts := range groups {
+			for hostName, opcacheStatus := range hosts {
+				// statistics not pulled yet
+				if opcacheStatus.Configuration == nil {
+					continue
+				}
+
+				node := NodeStatus{
+					Opcache: opcacheStatus,
+					Apcu:    apcuStatuses[clusterName][groupName][hostName],
+				}
+
+				for _, rule := range a.rules {
+					if rule.ProductionOnly && !a.productionClusters[clusterName] {
+						continue
+					}
+
+					finding := rule.Check(node)
+					if finding == nil {
+						continue
+					}
+
+					finding.RuleID = rule.ID
+					finding.ClusterName = clusterName
+					finding.GroupName = groupName
+					finding.HostName = hostName
+
+					findings = append(findings, *finding)
+				}
+			}
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].ClusterName != findings[j].ClusterName {
+			return findings[i].ClusterName < findings[j].ClusterName
+		}
+
+		if findings[i].GroupName != findings[j].GroupName {
+			return findings[i].GroupName < findings[j].GroupName
+		}
+
+		if findings[i].HostName != findings[j].HostName {
+			return findings[i].HostName < findings[j].HostName
+		}
+
+		return findings[i].RuleID < findings[j].RuleID
+	})
+
+	return findings
+}
@@ -0,0 +1,101 @@
+package advisor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+func buildOpcacheStatuses(configuration map[string]interface{}) observer.ClustersOpcacheStatuses {
+	status := observer.NodeOpcacheStatus{State: observer.NodeStateEnabled, Configuration: configuration}
+
+	return observer.ClustersOpcacheStatuses{
+		"production": {
+			"web": {"web2": status, "web1": status},
+			"api": {
+				"api1": status,
+				// statistics not pulled yet
+				"api2": {},
+			},
+		},
+		"staging": {
+			"web": {"web1": status},
+		},
+	}
+}
+
+func findingLocations(findings []Finding) []string {
+	locations := []string{}
+
+	for _, finding := range findings {
+		locations = append(locations, finding.ClusterName+"/"+finding.GroupName+"/"+finding.HostName+":"+finding.RuleID)
+	}
+
+	return locations
+}
+
+func TestAdvise(t *testing.T) {
+	statuses := buildOpcacheStatuses(map[string]interface{}{
+		"opcache.validate_timestamps":    true,
+		"opcache.revalidate_freq":        0.0,
+		"opcache.file_update_protection": 2.0,
+	})
+
+	settingsAdvisor := NewAdvisor(map[string]bool{}, map[string]bool{"production": true, "staging": false})
+
+	expected := []string{
+		"production/api/api1:revalidateFreq",
+		"production/api/api1:validateTimestamps",
+		"production/web/web1:revalidateFreq",
+		"production/web/web1:validateTimestamps",
+		"production/web/web2:revalidateFreq",
+		"production/web/web2:validateTimestamps",
+		// timestamps validation checked only in production
+		"staging/web/web1:revalidateFreq",
+	}
+
+	if locations := findingLocations(settingsAdvisor.Advise(statuses, observer.ClustersApcuStatuses{})); !reflect.DeepEqual(locations, expected) {
+		t.Errorf("got %v, want %v", locations, expected)
+	}
+}
+
+func TestAdviseWithoutProductionClusters(t *testing.T) {
+	statuses := buildOpcacheStatuses(map[string]interface{}{"opcache.validate_timestamps": true})
+
+	if findings := NewAdvisor(map[string]bool{}, map[string]bool{}).Advise(statuses, observer.ClustersApcuStatuses{}); len(findings) != 0 {
+		t.Errorf("unexpected findings %v", findingLocations(findings))
+	}
+}
+
+func TestAdviseDisabledRules(t *testing.T) {
+	statuses := buildOpcacheStatuses(map[string]interface{}{
+		"opcache.validate_timestamps": true,
+		"opcache.revalidate_freq":     0.0,
+	})
+
+	settingsAdvisor := NewAdvisor(map[string]bool{RuleRevalidateFreq: true}, map[string]bool{"production": true})
+
+	for _, finding := range settingsAdvisor.Advise(statuses, observer.ClustersApcuStatuses{}) {
+		if finding.RuleID != RuleValidateTimestamps {
+			t.Errorf("finding of disabled rule: %+v", finding)
+		}
+	}
+}
+
+func TestAdviseApcu(t *testing.T) {
+	statuses := buildOpcacheStatuses(map[string]interface{}{})
+	apcuStatuses := observer.ClustersApcuStatuses{
+		"production": {
+			"web": {"web1": apcuNode(1, 100).Apcu},
+			// APCu of node without pulled opcache statistics not checked
+			"api": {"api2": apcuNode(1, 100).Apcu},
+		},
+	}
+
+	findings := NewAdvisor(map[string]bool{}, map[string]bool{}).Advise(statuses, apcuStatuses)
+
+	if locations := findingLocations(findings); !reflect.DeepEqual(locations, []string{"production/web/web1:apcuShmSize"}) {
+		t.Errorf("got %v", locations)
+	}
+}
@@ -0,0 +1,249 @@
+package advisor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GoMetric/opcache-dashboard/alerting"
+)
+
+// Identifiers of rules, used to disable rules in configuration
+const (
+	RuleValidateTimestamps   = "validateTimestamps"
+	RuleRevalidateFreq       = "revalidateFreq"
+	RuleFileUpdateProtection = "fileUpdateProtection"
+	RuleOptimizationLevel    = "optimizationLevel"
+	RuleSaveComments         = "saveComments"
+	RuleApcuShmSize          = "apcuShmSize"
+)
+
+// Minimal recommended opcache.revalidate_freq when timestamps validated
+const minRevalidateFreq = 2
+
+// Part of APCu shared memory which must stay available
+const minApcuAvailableMemoryRatio = 0.1
+
+// Optimization passes which are safe and enabled by default, so disabling them slows down code.
+// Passes 14 and 16 are unsafe and disabled by default.
+var safeOptimizations = map[int]string{
+	0:  "Simple local optimizations",
+	1:  "Constant conversion and jumps",
+	2:  "Jump optimization",
+	3:  "INIT_FCALL_BY_NAME -> DO_FCALL",
+	4:  "CFG based optimization",
+	5:  "DFA based optimization",
+	6:  "CALL GRAPH optimization",
+	7:  "SCCP (constant propagation)",
+	8:  "TMP VAR usage",
+	9:  "NOP removal",
+	10: "Merge equal constants",
+	11: "Adjust used stack",
+	12: "Remove unused variables",
+	13: "DCE (dead code elimination)",
+	15: "Inline functions",
+}
+
+// Rules returns all known rules
+func Rules() []Rule {
+	return []Rule{
+		{
+			ID:             RuleValidateTimestamps,
+			ProductionOnly: true,
+			Check: func(node NodeStatus) *Finding {
+				value, ok := directiveBool(node, "opcache.validate_timestamps")
+				if !ok || !value {
+					return nil
+				}
+
+				return &Finding{
+					Severity:  alerting.SeverityWarning,
+					Directive: "opcache.validate_timestamps",
+					Value:     value,
+					Message: "Timestamps of scripts are checked for updates. In production code changes " +
+						"only on deploy, so disable validation and reset cache after deploy to avoid stat calls",
+				}
+			},
+		},
+		{
+			ID: RuleRevalidateFreq,
+			Check: func(node NodeStatus) *Finding {
+				validateTimestamps, ok := directiveBool(node, "opcache.validate_timestamps")
+				if !ok || !validateTimestamps {
+					return nil
+				}
+
+				value, ok := directiveNumber(node, "opcache.revalidate_freq")
+				if !ok || value >= minRevalidateFreq {
+					return nil
+				}
+
+				return &Finding{
+					Severity:  alerting.SeverityWarning,
+					Directive: "opcache.revalidate_freq",
+					Value:     value,
+					Message: fmt.Sprintf(
+						"Timestamps of scripts are checked every %.0f seconds, so nearly every request performs stat calls. "+
+							"Use at least %d seconds",
+						value,
+						minRevalidateFreq,
+					),
+				}
+			},
+		},
+		{
+			ID: RuleFileUpdateProtection,
+			Check: func(node NodeStatus) *Finding {
+				value, ok := directiveNumber(node, "opcache.file_update_protection")
+				if !ok || value > 0 {
+					return nil
+				}
+
+				return &Finding{
+					Severity:  alerting.SeverityWarning,
+					Directive: "opcache.file_update_protection",
+					Value:     value,
+					Message: "File update protection is disabled, so partially written scripts " +
+						"may be cached during deploy",
+				}
+			},
+		},
+		{
+			ID: RuleOptimizationLevel,
+			Check: func(node NodeStatus) *Finding {
+				// parser reports empty bitmap when directive absent or malformed, which is not a real setting
+				if _, ok := directiveNumber(node, "opcache.optimization_level"); !ok {
+					return nil
+				}
+
+				enabledOptimizations := map[int]bool{}
+				for _, optimizationID := range node.Opcache.Optimizations {
+					enabledOptimizations[optimizationID] = true
+				}
+
+				disabledOptimizations := []string{}
+				for optimizationID := 0; optimizationID <= 16; optimizationID++ {
+					optimizationName, isSafe := safeOptimizations[optimizationID]
+					if isSafe && !enabledOptimizations[optimizationID] {
+						disabledOptimizations = append(disabledOptimizations, optimizationName)
+					}
+				}
+
+				if len(disabledOptimizations) == 0 {
+					return nil
+				}
+
+				return &Finding{
+					Severity:  alerting.SeverityInfo,
+					Directive: "opcache.optimization_level",
+					Value:     node.Opcache.Configuration["opcache.optimization_level"],
+					Message: "Safe optimization passes disabled: " + strings.Join(disabledOptimizations, ", ") +
+						". Use default level 0x7FFEBFFF unless passes disabled intentionally",
+				}
+			},
+		},
+		{
+			ID: RuleSaveComments,
+			Check: func(node NodeStatus) *Finding {
+				value, ok := directiveBool(node, "opcache.save_comments")
+				if !ok || value {
+					return nil
+				}
+
+				return &Finding{
+					Severity:  alerting.SeverityWarning,
+					Directive: "opcache.save_comments",
+					Value:     value,
+					Message:   "Doc comments are dropped from cache, so libraries relying on annotations will break",
+				}
+			},
+		},
+		{
+			ID: RuleApcuShmSize,
+			Check: func(node NodeStatus) *Finding {
+				if !node.Apcu.Enabled || node.Apcu.SmaInfo == nil {
+					return nil
+				}
+
+				totalMemory := node.Apcu.SmaInfo.SegSize * node.Apcu.SmaInfo.NumSeg
+				if totalMemory == 0 {
+					return nil
+				}
+
+				availableRatio := float64(node.Apcu.SmaInfo.AvailMem) / float64(totalMemory)
+				if availableRatio >= minApcuAvailableMemoryRatio {
+					return nil
+				}
+
+				var shmSize interface{}
+				if node.Apcu.Settings != nil {
+					if setting, ok := (*node.Apcu.Settings)["apc.shm_size"]; ok {
+						shmSize = setting.LocalValue
+					}
+				}
+
+				return &Finding{
+					Severity:  alerting.SeverityCritical,
+					Directive: "apc.shm_size",
+					Value:     shmSize,
+					Message: fmt.Sprintf(
+						"Only %.1f%% of APCu shared memory available, entries will be expunged. Increase shared memory size",
+						availableRatio*100,
+					),
+				}
+			},
+		},
+	}
+}
+
+// directiveBool reads boolean opcache directive which may be passed as bool, number or string
+func directiveBool(node NodeStatus, directive string) (bool, bool) {
+	rawValue, ok := node.Opcache.Configuration[directive]
+	if !ok {
+		return false, false
+	}
+
+	switch value := rawValue.(type) {
+	case bool:
+		return value, true
+	case float64:
+		return value != 0, true
+	case string:
+		switch strings.ToLower(value) {
+		case "1", "on", "true", "yes":
+			return true, true
+		case "", "0", "off", "false", "no":
+			return false, true
+		}
+	}
+
+	return false, false
+}
+
+// directiveNumber reads numeric opcache directive which may be passed as number or string
+func directiveNumber(node NodeStatus, directive string) (float64, bool) {
+	rawValue, ok := node.Opcache.Configuration[directive]
+	if !ok {
+		return 0, false
+	}
+
+	switch value := rawValue.(type) {
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+
+		return 0, true
+	case string:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return number, true
+	}
+
+	return 0, false
+}
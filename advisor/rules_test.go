@@ -0,0 +1,192 @@
+package advisor
+
+import (
+	"testing"
+
+	"github.com/GoMetric/opcache-dashboard/alerting"
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+func findRule(t *testing.T, ruleID string) Rule {
+	t.Helper()
+
+	for _, rule := range Rules() {
+		if rule.ID == ruleID {
+			return rule
+		}
+	}
+
+	t.Fatalf("rule %s not found", ruleID)
+
+	return Rule{}
+}
+
+func opcacheNode(configuration map[string]interface{}, optimizations ...int) NodeStatus {
+	return NodeStatus{
+		Opcache: observer.NodeOpcacheStatus{
+			State:         observer.NodeStateEnabled,
+			Configuration: configuration,
+			Optimizations: optimizations,
+		},
+	}
+}
+
+func apcuNode(availableMemory int, totalMemory int) NodeStatus {
+	return NodeStatus{
+		Apcu: observer.NodeApcuStatus{
+			Enabled: true,
+			SmaInfo: &observer.NodeApcuSmaInfo{NumSeg: 1, SegSize: totalMemory, AvailMem: availableMemory},
+			Settings: &map[string]observer.NodeApcuSetting{
+				"apc.shm_size": {GlobalValue: "32M", LocalValue: "32M"},
+			},
+		},
+	}
+}
+
+func TestRules(t *testing.T) {
+	allOptimizations := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 15}
+
+	testCases := []struct {
+		name             string
+		ruleID           string
+		node             NodeStatus
+		expectedSeverity string // empty when no finding expected
+		expectedValue    interface{}
+	}{
+		{
+			name:             "timestamps validated",
+			ruleID:           RuleValidateTimestamps,
+			node:             opcacheNode(map[string]interface{}{"opcache.validate_timestamps": true}),
+			expectedSeverity: alerting.SeverityWarning,
+			expectedValue:    true,
+		},
+		{
+			name:             "timestamps validated, passed as string",
+			ruleID:           RuleValidateTimestamps,
+			node:             opcacheNode(map[string]interface{}{"opcache.validate_timestamps": "On"}),
+			expectedSeverity: alerting.SeverityWarning,
+			expectedValue:    true,
+		},
+		{
+			name:   "timestamps not validated",
+			ruleID: RuleValidateTimestamps,
+			node:   opcacheNode(map[string]interface{}{"opcache.validate_timestamps": 0.0}),
+		},
+		{
+			name:   "timestamps validation not reported",
+			ruleID: RuleValidateTimestamps,
+			node:   opcacheNode(map[string]interface{}{}),
+		},
+		{
+			name:   "malformed timestamps validation",
+			ruleID: RuleValidateTimestamps,
+			node:   opcacheNode(map[string]interface{}{"opcache.validate_timestamps": "sometimes"}),
+		},
+		{
+			name:   "revalidation frequency low",
+			ruleID: RuleRevalidateFreq,
+			node: opcacheNode(map[string]interface{}{
+				"opcache.validate_timestamps": true,
+				"opcache.revalidate_freq":     0.0,
+			}),
+			expectedSeverity: alerting.SeverityWarning,
+			expectedValue:    0.0,
+		},
+		{
+			name:   "revalidation frequency sufficient",
+			ruleID: RuleRevalidateFreq,
+			node: opcacheNode(map[string]interface{}{
+				"opcache.validate_timestamps": true,
+				"opcache.revalidate_freq":     "2",
+			}),
+		},
+		{
+			name:   "revalidation frequency low without timestamps validation",
+			ruleID: RuleRevalidateFreq,
+			node: opcacheNode(map[string]interface{}{
+				"opcache.validate_timestamps": false,
+				"opcache.revalidate_freq":     0.0,
+			}),
+		},
+		{
+			name:             "file update protection disabled",
+			ruleID:           RuleFileUpdateProtection,
+			node:             opcacheNode(map[string]interface{}{"opcache.file_update_protection": 0.0}),
+			expectedSeverity: alerting.SeverityWarning,
+			expectedValue:    0.0,
+		},
+		{
+			name:   "file update protection enabled",
+			ruleID: RuleFileUpdateProtection,
+			node:   opcacheNode(map[string]interface{}{"opcache.file_update_protection": 2.0}),
+		},
+		{
+			name:             "safe optimization disabled",
+			ruleID:           RuleOptimizationLevel,
+			node:             opcacheNode(map[string]interface{}{"opcache.optimization_level": 2147401727.0}, allOptimizations[1:]...),
+			expectedSeverity: alerting.SeverityInfo,
+			expectedValue:    2147401727.0,
+		},
+		{
+			name:   "default optimization level",
+			ruleID: RuleOptimizationLevel,
+			node:   opcacheNode(map[string]interface{}{"opcache.optimization_level": 2147401727.0}, allOptimizations...),
+		},
+		{
+			name:   "optimization level not reported",
+			ruleID: RuleOptimizationLevel,
+			node:   opcacheNode(map[string]interface{}{}),
+		},
+		{
+			name:             "comments not saved",
+			ruleID:           RuleSaveComments,
+			node:             opcacheNode(map[string]interface{}{"opcache.save_comments": "0"}),
+			expectedSeverity: alerting.SeverityWarning,
+			expectedValue:    false,
+		},
+		{
+			name:   "comments saved",
+			ruleID: RuleSaveComments,
+			node:   opcacheNode(map[string]interface{}{"opcache.save_comments": true}),
+		},
+		{
+			name:             "APCu memory exhausted",
+			ruleID:           RuleApcuShmSize,
+			node:             apcuNode(1, 100),
+			expectedSeverity: alerting.SeverityCritical,
+			expectedValue:    "32M",
+		},
+		{
+			name:   "APCu memory available",
+			ruleID: RuleApcuShmSize,
+			node:   apcuNode(50, 100),
+		},
+		{
+			name:   "APCu disabled",
+			ruleID: RuleApcuShmSize,
+			node:   NodeStatus{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			finding := findRule(t, testCase.ruleID).Check(testCase.node)
+
+			if testCase.expectedSeverity == "" {
+				if finding != nil {
+					t.Errorf("unexpected finding %+v", finding)
+				}
+
+				return
+			}
+
+			if finding == nil {
+				t.Fatal("expected finding")
+			}
+
+			if finding.Severity != testCase.expectedSeverity || finding.Value != testCase.expectedValue || finding.Message == "" {
+				t.Errorf("got finding %+v", finding)
+			}
+		})
+	}
+}
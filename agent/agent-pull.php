@@ -12,6 +12,19 @@
  */
 declare(strict_types=1);
 
+/**
+ * Latest version of protocol between dashboard and agent.
+ * Version 1 does not report protocol section and encodes scripts as object keyed by path.
+ * Version 2 reports protocol section first and encodes scripts as list.
+ */
+const AGENT_PROTOCOL_VERSION = 2;
+
+const AGENT_COMMANDS = ['status', 'reset', 'invalidate', 'capabilities', 'apcuKeys', 'apcuDelete', 'apcuClear'];
+
+const APCU_KEYS_MAX_LIMIT = 1000;
+
+const AGENT_SECTIONS = ['configuration', 'status', 'scripts', 'apcu'];
+
 /**
  * Check opcache extension configured
  */
@@ -26,13 +39,19 @@ if (!function_exists('opcache_get_status')) {
 /**
  * Router
  */
+$protocolVersion = negotiateProtocolVersion((int) filter_input(INPUT_GET, 'protocol'));
+
 $command = (string) filter_input(INPUT_GET, 'command');
 switch ($command) {
     case '':
     case 'status':
         $pretty = (bool) filter_input(INPUT_GET, 'pretty');
         $scripts = (bool) filter_input(INPUT_GET, 'scripts');
-        statusCommand($pretty, $scripts);
+        statusCommand($protocolVersion, $pretty, $scripts);
+        break;
+
+    case 'capabilities':
+        sendResponse(200, ['protocol' => getProtocol($protocolVersion)]);
         break;
 
     case 'reset':
@@ -43,12 +62,46 @@ switch ($command) {
         $scriptPath = (string) filter_input(INPUT_GET, 'script');
         invalidateCommand($scriptPath);
         break;
+
+    case 'apcuKeys':
+        $prefix = (string) filter_input(INPUT_GET, 'prefix');
+        $offset = (int) filter_input(INPUT_GET, 'offset');
+        $limit = (int) filter_input(INPUT_GET, 'limit');
+        apcuKeysCommand($prefix, $offset, $limit);
+        break;
+
+    case 'apcuDelete':
+        $key = (string) filter_input(INPUT_GET, 'key');
+        $prefix = (string) filter_input(INPUT_GET, 'prefix');
+        apcuDeleteCommand($key, $prefix);
+        break;
+
+    case 'apcuClear':
+        apcuClearCommand();
+        break;
     
     default:
         sendResponse(400, ['error' => 'Invalid command specified']);
         break;
 }
 
+/**
+ * Dashboards which do not request protocol version speak version 1
+ */
+function negotiateProtocolVersion(int $requestedVersion): int
+{
+    return max(1, min($requestedVersion, AGENT_PROTOCOL_VERSION));
+}
+
+function getProtocol(int $protocolVersion): array
+{
+    return [
+        'version' => $protocolVersion,
+        'commands' => AGENT_COMMANDS,
+        'sections' => AGENT_SECTIONS,
+    ];
+}
+
 /**
  * Complete reset of opcache
  */
@@ -79,16 +132,141 @@ function invalidateCommand(string $scriptPath): void
     sendResponse(200, ['error' => null]);
 }
 
+/**
+ * Page of APCu user cache entries, optionally filtered by key prefix
+ */
+function apcuKeysCommand(string $prefix, int $offset, int $limit): void
+{
+    if (!isApcuEnabled()) {
+        sendResponse(400, ['error' => 'APCu disabled']);
+        return;
+    }
+
+    $limit = $limit > 0 ? min($limit, APCU_KEYS_MAX_LIMIT) : APCU_KEYS_MAX_LIMIT;
+    $offset = max($offset, 0);
+
+    $iterator = new APCUIterator(
+        buildApcuKeyPrefixRegex($prefix),
+        APC_ITER_KEY | APC_ITER_NUM_HITS | APC_ITER_MEM_SIZE | APC_ITER_TTL | APC_ITER_CTIME | APC_ITER_ATIME | APC_ITER_MTIME
+    );
+
+    $entries = [];
+    $position = 0;
+    foreach ($iterator as $entry) {
+        if ($position++ < $offset) {
+            continue;
+        }
+
+        if (count($entries) >= $limit) {
+            break;
+        }
+
+        $entries[] = [
+            'key' => $entry['key'],
+            'size' => $entry['mem_size'],
+            'hits' => $entry['num_hits'],
+            'ttl' => $entry['ttl'],
+            'creation_time' => $entry['creation_time'],
+            'access_time' => $entry['access_time'],
+            'modification_time' => $entry['mtime'],
+        ];
+    }
+
+    sendResponse(
+        200,
+        [
+            'total' => $iterator->getTotalCount(),
+            'entries' => $entries,
+            'error' => null,
+        ]
+    );
+}
+
+/**
+ * Delete APCu user cache entry by exact key or all entries with key prefix
+ */
+function apcuDeleteCommand(string $key, string $prefix): void
+{
+    if (!isApcuEnabled()) {
+        sendResponse(400, ['error' => 'APCu disabled']);
+        return;
+    }
+
+    if ($key !== '') {
+        sendResponse(200, ['deleted' => apcu_delete($key) ? 1 : 0, 'error' => null]);
+        return;
+    }
+
+    if ($prefix === '') {
+        sendResponse(400, ['error' => 'Key or prefix not defined']);
+        return;
+    }
+
+    $iterator = new APCUIterator(buildApcuKeyPrefixRegex($prefix), APC_ITER_KEY);
+    $deleted = 0;
+    foreach ($iterator as $entry) {
+        if (apcu_delete($entry['key'])) {
+            $deleted++;
+        }
+    }
+
+    sendResponse(200, ['deleted' => $deleted, 'error' => null]);
+}
+
+/**
+ * Clear whole APCu user cache
+ */
+function apcuClearCommand(): void
+{
+    if (!isApcuEnabled()) {
+        sendResponse(400, ['error' => 'APCu disabled']);
+        return;
+    }
+
+    apcu_clear_cache();
+
+    sendResponse(200, ['error' => null]);
+}
+
+function buildApcuKeyPrefixRegex(string $prefix): ?string
+{
+    if ($prefix === '') {
+        return null;
+    }
+
+    return '/^' . preg_quote($prefix, '/') . '/';
+}
+
 /**
  * Status command return status of OPcache
  */
-function statusCommand(bool $pretty, bool $scripts): void
-{   
+function statusCommand(int $protocolVersion, bool $pretty, bool $scripts): void
+{
+    $status = opcache_get_status($scripts);
+
+    if ($protocolVersion === 1) {
+        sendResponse(
+            200,
+            [
+                'configuration' => opcache_get_configuration(),
+                'status' => $status,
+                'apcu' => getApcuStatus(),
+            ],
+            $pretty
+        );
+        return;
+    }
+
+    if (is_array($status) && isset($status['scripts'])) {
+        $status['scripts'] = array_values($status['scripts']);
+    }
+
     sendResponse(
         200,
         [
+            'protocol' => getProtocol($protocolVersion),
             'configuration' => opcache_get_configuration(),
-            'status' => opcache_get_status($scripts),
+            'status' => $status,
             'apcu' => getApcuStatus(),
         ],
         $pretty
@@ -112,9 +290,14 @@ function sendResponse(int $code, array $body, bool $pretty = false): void
     );
 }
 
+function isApcuEnabled(): bool
+{
+    return extension_loaded('apcu') && apcu_enabled();
+}
+
 function getApcuStatus(): array
 {
-    if (!extension_loaded('apcu') || !apcu_enabled()) {
+    if (!isApcuEnabled()) {
         return [
             'enabled' => false,
         ];
@@ -123,6 +306,7 @@ function getApcuStatus(): array
     return [
         'enabled' => true,
         'smaInfo' => apcu_sma_info(),
+        'cacheInfo' => apcu_cache_info(true),
         'settings' => ini_get_all('apcu'),
     ];
 }
@@ -0,0 +1,129 @@
+package alerting
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Severities of alerts
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert represents problem detected in observed nodes
+type Alert struct {
+	// ID identifies alert condition, so same condition raised again does not duplicate alert
+	ID          string
+	Type        string
+	Severity    string
+	ClusterName string
+	GroupName   string
+	HostName    string // empty when alert relates to whole group or cluster
+	Message     string
+	RaisedAt    time.Time
+}
+
+// AlertListener receives alerts on raise and on resolve
+type AlertListener func(alert Alert, resolved bool)
+
+// Registry holds active alerts
+type Registry struct {
+	mutex     sync.RWMutex
+	active    map[string]Alert
+	listeners []AlertListener
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		active: map[string]Alert{},
+	}
+}
+
+// AddListener registers listener notified when alert raised or resolved
+func (r *Registry) AddListener(listener AlertListener) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// Raise activates alert. Listeners notified only when alert was not active yet.
+func (r *Registry) Raise(alert Alert) {
+	r.mutex.Lock()
+
+	if _, ok := r.active[alert.ID]; ok {
+		r.mutex.Unlock()
+		return
+	}
+
+	if alert.RaisedAt.IsZero() {
+		alert.RaisedAt = time.Now()
+	}
+
+	r.active[alert.ID] = alert
+	listeners := r.listeners
+
+	r.mutex.Unlock()
+
+	log.Printf("Alert raised: %s", alert.Message)
+
+	for _, listener := range listeners {
+		listener(alert, false)
+	}
+}
+
+// Resolve deactivates alert if it is active
+func (r *Registry) Resolve(id string) {
+	r.mutex.Lock()
+
+	alert, ok := r.active[id]
+	if !ok {
+		r.mutex.Unlock()
+		return
+	}
+
+	delete(r.active, id)
+	listeners := r.listeners
+
+	r.mutex.Unlock()
+
+	log.Printf("Alert resolved: %s", alert.Message)
+
+	for _, listener := range listeners {
+		listener(alert, true)
+	}
+}
+
+// Set raises or resolves alert depending on condition
+func (r *Registry) Set(alert Alert, isActive bool) {
+	if isActive {
+		r.Raise(alert)
+	} else {
+		r.Resolve(alert.ID)
+	}
+}
+
+// Active returns active alerts ordered by raise time
+func (r *Registry) Active() []Alert {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	alerts := make([]Alert, 0, len(r.active))
+	for _, alert := range r.active {
+		alerts = append(alerts, alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].RaisedAt.Equal(alerts[j].RaisedAt) {
+			return alerts[i].RaisedAt.Before(alerts[j].RaisedAt)
+		}
+
+		return alerts[i].ID < alerts[j].ID
+	})
+
+	return alerts
+}
@@ -0,0 +1,71 @@
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoMetric/opcache-dashboard/analytics"
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// AlertTypeConfigurationDrift raised when nodes of one group have different opcache configuration
+const AlertTypeConfigurationDrift = "configurationDrift"
+
+// CheckConfigurationDrift raises alert for every group which nodes have different
+// opcache configuration or PHP version, and resolves alerts of groups without drift
+func CheckConfigurationDrift(registry *Registry, statuses observer.ClustersOpcacheStatuses) {
+	for clusterName, groups := range statuses {
+		for groupName := range groups {
+			drift, err := analytics.DetectConfigurationDrift(statuses, clusterName, groupName)
+			if err != nil {
+				continue
+			}
+
+			alert := Alert{
+				ID:          fmt.Sprintf("%s:%s:%s", AlertTypeConfigurationDrift, clusterName, groupName),
+				Type:        AlertTypeConfigurationDrift,
+				Severity:    SeverityWarning,
+				ClusterName: clusterName,
+				GroupName:   groupName,
+			}
+
+			if drift.HasDrift {
+				alert.Message = buildConfigurationDriftMessage(drift)
+			}
+
+			registry.Set(alert, drift.HasDrift)
+		}
+	}
+}
+
+func buildConfigurationDriftMessage(drift *analytics.ConfigurationDrift) string {
+	details := []string{}
+
+	if len(drift.DifferentDirectives) > 0 {
+		directiveNames := make([]string, 0, len(drift.DifferentDirectives))
+		for _, difference := range drift.DifferentDirectives {
+			directiveNames = append(directiveNames, difference.Directive)
+		}
+
+		details = append(details, "different directives "+strings.Join(directiveNames, ", "))
+	}
+
+	if drift.PHPVersionMismatch {
+		phpVersions := make([]string, 0, len(drift.PHPVersions))
+		for phpVersion := range drift.PHPVersions {
+			phpVersions = append(phpVersions, phpVersion)
+		}
+
+		sort.Strings(phpVersions)
+
+		details = append(details, "different PHP versions "+strings.Join(phpVersions, ", "))
+	}
+
+	return fmt.Sprintf(
+		"Configuration drift in group %s of cluster %s: %s",
+		drift.GroupName,
+		drift.ClusterName,
+		strings.Join(details, "; "),
+	)
+}
@@ -0,0 +1,54 @@
+package alerting
+
+import (
+	"fmt"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// AlertTypeJitBufferExhausted raised when JIT buffer of node is full, so new code is not compiled anymore
+const AlertTypeJitBufferExhausted = "jitBufferExhausted"
+
+// JitBufferExhaustedFreePercentage is share of free JIT buffer below which buffer considered exhausted
+const JitBufferExhaustedFreePercentage = 1.0
+
+// CheckJitBufferExhausted raises alert for every node which JIT buffer is exhausted,
+// and resolves alerts of nodes with free JIT buffer
+func CheckJitBufferExhausted(registry *Registry, statuses observer.ClustersOpcacheStatuses) {
+	for clusterName, groups := range statuses {
+		for groupName, hosts := range groups {
+			for hostName, status := range hosts {
+				// statistics not pulled yet
+				if status.Configuration == nil {
+					continue
+				}
+
+				alert := Alert{
+					ID:          fmt.Sprintf("%s:%s:%s:%s", AlertTypeJitBufferExhausted, clusterName, groupName, hostName),
+					Type:        AlertTypeJitBufferExhausted,
+					Severity:    SeverityWarning,
+					ClusterName: clusterName,
+					GroupName:   groupName,
+					HostName:    hostName,
+				}
+
+				isExhausted := false
+				if jit := status.Jit; jit != nil && jit.Enabled && jit.BufferSize > 0 {
+					freePercentage := 100 * float64(jit.BufferFree) / float64(jit.BufferSize)
+					isExhausted = freePercentage < JitBufferExhaustedFreePercentage
+
+					alert.Message = fmt.Sprintf(
+						"JIT buffer of node %s in group %s of cluster %s exhausted: %d of %d bytes free, increase opcache.jit_buffer_size",
+						hostName,
+						groupName,
+						clusterName,
+						jit.BufferFree,
+						jit.BufferSize,
+					)
+				}
+
+				registry.Set(alert, isExhausted)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// GroupScriptCoverage compares cached scripts of nodes in one group.
+// Nodes of group expected to serve same code, so they must cache same scripts.
+type GroupScriptCoverage struct {
+	ClusterName string
+	GroupName   string
+	// Hosts with pulled scripts taken into comparison
+	Hosts []string
+	// Hosts without pulled scripts yet, excluded from comparison
+	UnavailableHosts []string
+	ScriptsCount     int
+	// Count of scripts cached on every compared host
+	CommonScriptsCount int
+	Nodes              map[string]NodeScriptCoverage
+	// Scripts which are not cached on every compared host
+	PartialScripts []ScriptPresence
+	// Scripts with different modification timestamps on different hosts
+	TimestampMismatches []ScriptTimestampMismatch
+}
+
+// NodeScriptCoverage describes difference of node scripts from other nodes of group
+type NodeScriptCoverage struct {
+	ScriptsCount int
+	// Scripts cached on most nodes of group but absent on this node
+	MissingScripts []string
+	// Scripts cached on this node but absent on most nodes of group
+	ExtraScripts []string
+}
+
+// ScriptPresence describes hosts where script cached
+type ScriptPresence struct {
+	Path      string
+	PresentOn []string
+	MissingOn []string
+}
+
+// ScriptTimestampMismatch holds script modification timestamps by host
+type ScriptTimestampMismatch struct {
+	Path             string
+	CreateTimestamps map[string]int64
+}
+
+// CompareGroupScripts builds per-script presence across hosts of group
+func CompareGroupScripts(
+	statuses observer.ClustersOpcacheStatuses,
+	clusterName string,
+	groupName string,
+) (*GroupScriptCoverage, error) {
+	if groupName == "" {
+		return nil, ErrScopeNotFound
+	}
+
+	nodes, err := SelectNodes(statuses, clusterName, groupName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := &GroupScriptCoverage{
+		ClusterName:         clusterName,
+		GroupName:           groupName,
+		Hosts:               []string{},
+		UnavailableHosts:    []string{},
+		Nodes:               map[string]NodeScriptCoverage{},
+		PartialScripts:      []ScriptPresence{},
+		TimestampMismatches: []ScriptTimestampMismatch{},
+	}
+
+	// script path => host name => script
+	scriptHosts := map[string]map[string]observer.Script{}
+
+	for _, node := range nodes {
+		if node.Status.Scripts == nil {
+			coverage.UnavailableHosts = append(coverage.UnavailableHosts, node.HostName)
+			continue
+		}
+
+		coverage.Hosts = append(coverage.Hosts, node.HostName)
+		coverage.Nodes[node.HostName] = NodeScriptCoverage{
+			ScriptsCount:   len(node.Status.Scripts),
+			MissingScripts: []string{},
+			ExtraScripts:   []string{},
+		}
+
+		for path, script := range node.Status.Scripts {
+			if _, ok := scriptHosts[path]; !ok {
+				scriptHosts[path] = map[string]observer.Script{}
+			}
+
+			scriptHosts[path][node.HostName] = script
+		}
+	}
+
+	coverage.ScriptsCount = len(scriptHosts)
+
+	paths := make([]string, 0, len(scriptHosts))
+	for path := range scriptHosts {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	hostsCount := len(coverage.Hosts)
+
+	for _, path := range paths {
+		hosts := scriptHosts[path]
+
+		// timestamps
+		if mismatch, ok := findTimestampMismatch(path, hosts); ok {
+			coverage.TimestampMismatches = append(coverage.TimestampMismatches, mismatch)
+		}
+
+		if len(hosts) == hostsCount {
+			coverage.CommonScriptsCount++
+			continue
+		}
+
+		// presence
+		presence := ScriptPresence{
+			Path:      path,
+			PresentOn: []string{},
+			MissingOn: []string{},
+		}
+
+		isCachedOnMostHosts := len(hosts)*2 > hostsCount
+
+		for _, hostName := range coverage.Hosts {
+			nodeCoverage := coverage.Nodes[hostName]
+
+			if _, ok := hosts[hostName]; ok {
+				presence.PresentOn = append(presence.PresentOn, hostName)
+
+				if !isCachedOnMostHosts {
+					nodeCoverage.ExtraScripts = append(nodeCoverage.ExtraScripts, path)
+				}
+			} else {
+				presence.MissingOn = append(presence.MissingOn, hostName)
+
+				if isCachedOnMostHosts {
+					nodeCoverage.MissingScripts = append(nodeCoverage.MissingScripts, path)
+				}
+			}
+
+			coverage.Nodes[hostName] = nodeCoverage
+		}
+
+		coverage.PartialScripts = append(coverage.PartialScripts, presence)
+	}
+
+	return coverage, nil
+}
+
+func findTimestampMismatch(path string, hosts map[string]observer.Script) (ScriptTimestampMismatch, bool) {
+	mismatch := ScriptTimestampMismatch{
+		Path:             path,
+		CreateTimestamps: map[string]int64{},
+	}
+
+	isMismatched := false
+	var firstTimestamp *int64
+
+	for hostName, script := range hosts {
+		createTimestamp := script.CreateTimestamp
+		mismatch.CreateTimestamps[hostName] = createTimestamp
+
+		if firstTimestamp == nil {
+			firstTimestamp = &createTimestamp
+		} else if *firstTimestamp != createTimestamp {
+			isMismatched = true
+		}
+	}
+
+	return mismatch, isMismatched
+}
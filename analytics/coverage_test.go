@@ -0,0 +1,125 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+func TestCompareGroupScripts(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{
+		"web/web1": {
+			"/index.php":  {CreateTimestamp: 100},
+			"/Kernel.php": {CreateTimestamp: 100},
+			"/debug.php":  {CreateTimestamp: 100},
+		},
+		"web/web2": {
+			"/index.php":  {CreateTimestamp: 100},
+			"/Kernel.php": {CreateTimestamp: 200},
+		},
+		"web/web3": {
+			"/index.php":  {CreateTimestamp: 100},
+			"/Kernel.php": {CreateTimestamp: 100},
+		},
+		// scripts of node not pulled yet
+		"web/web4": nil,
+	})
+
+	coverage, err := CompareGroupScripts(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(coverage.Hosts, []string{"web1", "web2", "web3"}) ||
+		!reflect.DeepEqual(coverage.UnavailableHosts, []string{"web4"}) {
+		t.Errorf("got hosts %v, unavailable %v", coverage.Hosts, coverage.UnavailableHosts)
+	}
+
+	if coverage.ScriptsCount != 3 || coverage.CommonScriptsCount != 2 {
+		t.Errorf("got %d scripts, %d common", coverage.ScriptsCount, coverage.CommonScriptsCount)
+	}
+
+	expectedPartial := []ScriptPresence{
+		{Path: "/debug.php", PresentOn: []string{"web1"}, MissingOn: []string{"web2", "web3"}},
+	}
+	if !reflect.DeepEqual(coverage.PartialScripts, expectedPartial) {
+		t.Errorf("partial scripts: got %+v", coverage.PartialScripts)
+	}
+
+	if extra := coverage.Nodes["web1"].ExtraScripts; !reflect.DeepEqual(extra, []string{"/debug.php"}) {
+		t.Errorf("extra scripts of web1: got %v", extra)
+	}
+
+	if missing := coverage.Nodes["web2"].MissingScripts; len(missing) != 0 {
+		t.Errorf("script cached on minority of hosts reported missing on web2: %v", missing)
+	}
+
+	expectedMismatches := []ScriptTimestampMismatch{
+		{Path: "/Kernel.php", CreateTimestamps: map[string]int64{"web1": 100, "web2": 200, "web3": 100}},
+	}
+	if !reflect.DeepEqual(coverage.TimestampMismatches, expectedMismatches) {
+		t.Errorf("timestamp mismatches: got %+v", coverage.TimestampMismatches)
+	}
+}
+
+func TestCompareGroupScriptsMissingOnMinority(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{
+		"web/web1": {"/index.php": {}, "/Kernel.php": {}},
+		"web/web2": {"/index.php": {}, "/Kernel.php": {}},
+		"web/web3": {"/index.php": {}},
+	})
+
+	coverage, err := CompareGroupScripts(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if missing := coverage.Nodes["web3"].MissingScripts; !reflect.DeepEqual(missing, []string{"/Kernel.php"}) {
+		t.Errorf("missing scripts of web3: got %v", missing)
+	}
+
+	for _, hostName := range []string{"web1", "web2"} {
+		if extra := coverage.Nodes[hostName].ExtraScripts; len(extra) != 0 {
+			t.Errorf("script cached on most hosts reported extra on %s: %v", hostName, extra)
+		}
+	}
+}
+
+func TestCompareGroupScriptsSingleNode(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{
+		"web/web1": {"/index.php": {}, "/Kernel.php": {}},
+	})
+
+	coverage, err := CompareGroupScripts(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if coverage.CommonScriptsCount != 2 || len(coverage.PartialScripts) != 0 || len(coverage.TimestampMismatches) != 0 {
+		t.Errorf("single node must cover all its scripts: %+v", coverage)
+	}
+}
+
+func TestCompareGroupScriptsWithoutPulledScripts(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{"web/web1": nil, "web/web2": nil})
+
+	coverage, err := CompareGroupScripts(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(coverage.Hosts) != 0 || coverage.ScriptsCount != 0 || len(coverage.UnavailableHosts) != 2 {
+		t.Errorf("got %+v", coverage)
+	}
+}
+
+func TestCompareGroupScriptsScopeNotFound(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{"web/web1": {}})
+
+	for _, groupName := range []string{"", "api"} {
+		if _, err := CompareGroupScripts(statuses, "production", groupName); err != ErrScopeNotFound {
+			t.Errorf("group %q: got error %v", groupName, err)
+		}
+	}
+}
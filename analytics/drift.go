@@ -0,0 +1,177 @@
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// NodeRef identifies node in cluster
+type NodeRef struct {
+	GroupName string
+	HostName  string
+}
+
+// ConfigurationDrift describes differences of opcache configuration between nodes of cluster or group
+type ConfigurationDrift struct {
+	ClusterName string
+	GroupName   string // empty when whole cluster compared
+	HasDrift    bool
+	// Nodes grouped by fingerprint of opcache directives
+	Fingerprints []ConfigurationFingerprint
+	// Directives which values differ between nodes
+	DifferentDirectives []DirectiveDifference
+	// Hosts grouped by PHP version
+	PHPVersions        map[string][]NodeRef
+	PHPVersionMismatch bool
+	// Nodes without pulled configuration yet, excluded from comparison
+	UnavailableNodes []NodeRef
+}
+
+// ConfigurationFingerprint represents nodes sharing same opcache directives
+type ConfigurationFingerprint struct {
+	Fingerprint string
+	Nodes       []NodeRef
+}
+
+// DirectiveDifference lists nodes by value of directive
+type DirectiveDifference struct {
+	Directive string
+	Values    []DirectiveValue
+}
+
+// DirectiveValue holds nodes with same value of directive. Nil value means directive absent.
+type DirectiveValue struct {
+	Value interface{}
+	Nodes []NodeRef
+}
+
+// DetectConfigurationDrift compares opcache directives and PHP versions of nodes in cluster or group
+func DetectConfigurationDrift(
+	statuses observer.ClustersOpcacheStatuses,
+	clusterName string,
+	groupName string,
+) (*ConfigurationDrift, error) {
+	nodes, err := SelectNodes(statuses, clusterName, groupName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &ConfigurationDrift{
+		ClusterName:         clusterName,
+		GroupName:           groupName,
+		Fingerprints:        []ConfigurationFingerprint{},
+		DifferentDirectives: []DirectiveDifference{},
+		PHPVersions:         map[string][]NodeRef{},
+		UnavailableNodes:    []NodeRef{},
+	}
+
+	comparedNodes := []Node{}
+	fingerprintNodes := map[string][]NodeRef{}
+	directiveNames := map[string]bool{}
+
+	for _, node := range nodes {
+		nodeRef := NodeRef{node.GroupName, node.HostName}
+
+		if node.Status.Configuration == nil {
+			drift.UnavailableNodes = append(drift.UnavailableNodes, nodeRef)
+			continue
+		}
+
+		comparedNodes = append(comparedNodes, node)
+
+		fingerprint := ConfigurationFingerprintOf(node.Status.Configuration)
+		fingerprintNodes[fingerprint] = append(fingerprintNodes[fingerprint], nodeRef)
+
+		drift.PHPVersions[node.Status.PHPVersion] = append(drift.PHPVersions[node.Status.PHPVersion], nodeRef)
+
+		for directiveName := range node.Status.Configuration {
+			directiveNames[directiveName] = true
+		}
+	}
+
+	// fingerprints, largest group of nodes first
+	for fingerprint, fingerprintNodeRefs := range fingerprintNodes {
+		drift.Fingerprints = append(drift.Fingerprints, ConfigurationFingerprint{
+			Fingerprint: fingerprint,
+			Nodes:       fingerprintNodeRefs,
+		})
+	}
+
+	sort.Slice(drift.Fingerprints, func(i, j int) bool {
+		if len(drift.Fingerprints[i].Nodes) != len(drift.Fingerprints[j].Nodes) {
+			return len(drift.Fingerprints[i].Nodes) > len(drift.Fingerprints[j].Nodes)
+		}
+
+		return drift.Fingerprints[i].Fingerprint < drift.Fingerprints[j].Fingerprint
+	})
+
+	// differences of directives
+	if len(drift.Fingerprints) > 1 {
+		sortedDirectiveNames := make([]string, 0, len(directiveNames))
+		for directiveName := range directiveNames {
+			sortedDirectiveNames = append(sortedDirectiveNames, directiveName)
+		}
+
+		sort.Strings(sortedDirectiveNames)
+
+		for _, directiveName := range sortedDirectiveNames {
+			if difference, ok := findDirectiveDifference(directiveName, comparedNodes); ok {
+				drift.DifferentDirectives = append(drift.DifferentDirectives, difference)
+			}
+		}
+	}
+
+	drift.PHPVersionMismatch = len(drift.PHPVersions) > 1
+	drift.HasDrift = len(drift.Fingerprints) > 1 || drift.PHPVersionMismatch
+
+	return drift, nil
+}
+
+// ConfigurationFingerprintOf builds short hash of directives which is equal for equal configurations
+func ConfigurationFingerprintOf(directives map[string]interface{}) string {
+	// map keys are marshaled in sorted order, so JSON is canonical
+	directivesJSON, _ := json.Marshal(directives)
+	hash := sha256.Sum256(directivesJSON)
+
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+func findDirectiveDifference(directiveName string, nodes []Node) (DirectiveDifference, bool) {
+	difference := DirectiveDifference{
+		Directive: directiveName,
+		Values:    []DirectiveValue{},
+	}
+
+	valueIndexes := map[string]int{}
+
+	for _, node := range nodes {
+		value, ok := node.Status.Configuration[directiveName]
+		if !ok {
+			value = nil
+		}
+
+		valueKey := fmt.Sprintf("%T:%v", value, value)
+
+		valueIndex, ok := valueIndexes[valueKey]
+		if !ok {
+			valueIndex = len(difference.Values)
+			valueIndexes[valueKey] = valueIndex
+			difference.Values = append(difference.Values, DirectiveValue{
+				Value: value,
+				Nodes: []NodeRef{},
+			})
+		}
+
+		difference.Values[valueIndex].Nodes = append(
+			difference.Values[valueIndex].Nodes,
+			NodeRef{node.GroupName, node.HostName},
+		)
+	}
+
+	return difference, len(difference.Values) > 1
+}
@@ -0,0 +1,177 @@
+package analytics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+type testNodeConfiguration struct {
+	phpVersion string
+	directives map[string]interface{}
+}
+
+// buildConfigurationStatuses builds statuses of cluster "production", node key is "group/host"
+func buildConfigurationStatuses(nodes map[string]testNodeConfiguration) observer.ClustersOpcacheStatuses {
+	statuses := observer.ClustersOpcacheStatuses{"production": {}}
+
+	for node, configuration := range nodes {
+		groupName, hostName, _ := strings.Cut(node, "/")
+
+		if _, ok := statuses["production"][groupName]; !ok {
+			statuses["production"][groupName] = map[string]observer.NodeOpcacheStatus{}
+		}
+
+		statuses["production"][groupName][hostName] = observer.NodeOpcacheStatus{
+			PHPVersion:    configuration.phpVersion,
+			Configuration: configuration.directives,
+		}
+	}
+
+	return statuses
+}
+
+func TestDetectConfigurationDrift(t *testing.T) {
+	statuses := buildConfigurationStatuses(map[string]testNodeConfiguration{
+		"web/web1": {"8.2.24", map[string]interface{}{"opcache.memory_consumption": 128.0, "opcache.jit": "tracing"}},
+		"web/web2": {"8.2.24", map[string]interface{}{"opcache.memory_consumption": 128.0, "opcache.jit": "tracing"}},
+		"web/web3": {"8.2.24", map[string]interface{}{"opcache.memory_consumption": 256.0}},
+		// configuration not pulled yet
+		"web/web4": {"", nil},
+	})
+
+	drift, err := DetectConfigurationDrift(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !drift.HasDrift || drift.PHPVersionMismatch {
+		t.Errorf("got drift %v, PHP version mismatch %v", drift.HasDrift, drift.PHPVersionMismatch)
+	}
+
+	if !reflect.DeepEqual(drift.UnavailableNodes, []NodeRef{{"web", "web4"}}) {
+		t.Errorf("unavailable nodes: got %v", drift.UnavailableNodes)
+	}
+
+	// largest group of nodes first
+	if len(drift.Fingerprints) != 2 ||
+		!reflect.DeepEqual(drift.Fingerprints[0].Nodes, []NodeRef{{"web", "web1"}, {"web", "web2"}}) ||
+		!reflect.DeepEqual(drift.Fingerprints[1].Nodes, []NodeRef{{"web", "web3"}}) {
+		t.Errorf("fingerprints: got %+v", drift.Fingerprints)
+	}
+
+	expectedDifferences := []DirectiveDifference{
+		{
+			Directive: "opcache.jit",
+			Values: []DirectiveValue{
+				{Value: "tracing", Nodes: []NodeRef{{"web", "web1"}, {"web", "web2"}}},
+				{Value: nil, Nodes: []NodeRef{{"web", "web3"}}},
+			},
+		},
+		{
+			Directive: "opcache.memory_consumption",
+			Values: []DirectiveValue{
+				{Value: 128.0, Nodes: []NodeRef{{"web", "web1"}, {"web", "web2"}}},
+				{Value: 256.0, Nodes: []NodeRef{{"web", "web3"}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(drift.DifferentDirectives, expectedDifferences) {
+		t.Errorf("different directives: got %+v", drift.DifferentDirectives)
+	}
+}
+
+func TestDetectConfigurationDriftWithoutDrift(t *testing.T) {
+	testCases := map[string]map[string]testNodeConfiguration{
+		"single node": {
+			"web/web1": {"8.2.24", map[string]interface{}{"opcache.enable": true}},
+		},
+		"equal nodes": {
+			"web/web1": {"8.2.24", map[string]interface{}{"opcache.enable": true}},
+			"web/web2": {"8.2.24", map[string]interface{}{"opcache.enable": true}},
+		},
+		"configuration of other nodes missing": {
+			"web/web1": {"8.2.24", map[string]interface{}{"opcache.enable": true}},
+			"web/web2": {"", nil},
+		},
+		"no configuration pulled": {
+			"web/web1": {"", nil},
+			"web/web2": {"", nil},
+		},
+	}
+
+	for name, nodes := range testCases {
+		t.Run(name, func(t *testing.T) {
+			drift, err := DetectConfigurationDrift(buildConfigurationStatuses(nodes), "production", "web")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if drift.HasDrift || len(drift.DifferentDirectives) != 0 || len(drift.Fingerprints) > 1 {
+				t.Errorf("unexpected drift: %+v", drift)
+			}
+		})
+	}
+}
+
+func TestDetectConfigurationDriftOfPHPVersion(t *testing.T) {
+	statuses := buildConfigurationStatuses(map[string]testNodeConfiguration{
+		"web/web1": {"8.2.24", map[string]interface{}{"opcache.enable": true}},
+		"api/api1": {"8.3.12", map[string]interface{}{"opcache.enable": true}},
+	})
+
+	drift, err := DetectConfigurationDrift(statuses, "production", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !drift.HasDrift || !drift.PHPVersionMismatch || len(drift.Fingerprints) != 1 {
+		t.Errorf("got %+v", drift)
+	}
+
+	expectedVersions := map[string][]NodeRef{"8.2.24": {{"web", "web1"}}, "8.3.12": {{"api", "api1"}}}
+	if !reflect.DeepEqual(drift.PHPVersions, expectedVersions) {
+		t.Errorf("PHP versions: got %v", drift.PHPVersions)
+	}
+}
+
+func TestDetectConfigurationDriftOfValueType(t *testing.T) {
+	// agents of different versions may report same directive with different types
+	statuses := buildConfigurationStatuses(map[string]testNodeConfiguration{
+		"web/web1": {"8.2.24", map[string]interface{}{"opcache.enable": true}},
+		"web/web2": {"8.2.24", map[string]interface{}{"opcache.enable": "1"}},
+	})
+
+	drift, err := DetectConfigurationDrift(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !drift.HasDrift || len(drift.DifferentDirectives) != 1 || len(drift.DifferentDirectives[0].Values) != 2 {
+		t.Errorf("got %+v", drift)
+	}
+}
+
+func TestDetectConfigurationDriftScopeNotFound(t *testing.T) {
+	statuses := buildConfigurationStatuses(map[string]testNodeConfiguration{"web/web1": {"8.2.24", nil}})
+
+	if _, err := DetectConfigurationDrift(statuses, "staging", ""); err != ErrScopeNotFound {
+		t.Errorf("got error %v", err)
+	}
+
+	if _, err := DetectConfigurationDrift(statuses, "production", "api"); err != ErrScopeNotFound {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestConfigurationFingerprintOf(t *testing.T) {
+	first := ConfigurationFingerprintOf(map[string]interface{}{"a": 1.0, "b": "x"})
+	second := ConfigurationFingerprintOf(map[string]interface{}{"b": "x", "a": 1.0})
+	other := ConfigurationFingerprintOf(map[string]interface{}{"a": 2.0, "b": "x"})
+
+	if first != second || first == other || len(first) != 12 {
+		t.Errorf("got fingerprints %s, %s, %s", first, second, other)
+	}
+}
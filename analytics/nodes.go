@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// Node represents opcache status of single node with its location in cluster
+type Node struct {
+	ClusterName string
+	GroupName   string
+	HostName    string
+	Status      observer.NodeOpcacheStatus
+}
+
+// SelectNodes returns nodes of cluster, group or single host sorted by group and host name.
+// Empty group or host name means all groups of cluster or all hosts of group.
+func SelectNodes(
+	statuses observer.ClustersOpcacheStatuses,
+	clusterName string,
+	groupName string,
+	hostName string,
+) ([]Node, error) {
+	groups, ok := statuses[clusterName]
+	if !ok {
+		return nil, ErrScopeNotFound
+	}
+
+	if groupName != "" {
+		if _, ok := groups[groupName]; !ok {
+			return nil, ErrScopeNotFound
+		}
+	}
+
+	nodes := []Node{}
+
+	for currentGroupName, hosts := range groups {
+		if groupName != "" && currentGroupName != groupName {
+			continue
+		}
+
+		if hostName != "" {
+			status, ok := hosts[hostName]
+			if !ok {
+				continue
+			}
+
+			nodes = append(nodes, Node{clusterName, currentGroupName, hostName, status})
+			continue
+		}
+
+		for currentHostName, status := range hosts {
+			nodes = append(nodes, Node{clusterName, currentGroupName, currentHostName, status})
+		}
+	}
+
+	if hostName != "" && len(nodes) == 0 {
+		return nil, ErrScopeNotFound
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].GroupName != nodes[j].GroupName {
+			return nodes[i].GroupName < nodes[j].GroupName
+		}
+
+		return nodes[i].HostName < nodes[j].HostName
+	})
+
+	return nodes, nil
+}
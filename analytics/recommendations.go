@@ -0,0 +1,334 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+const bytesInMegabyte = 1024 * 1024
+
+// Headroom multipliers applied to observed usage
+const keysHeadroom = 1.25
+const memoryHeadroom = 1.25
+const internedStringsHeadroom = 1.5
+
+// Part of usage which is considered as almost full
+const highUsageRatio = 0.9
+
+// Limits of opcache.max_accelerated_files in php.ini
+const minMaxAcceleratedFiles = 200
+const maxMaxAcceleratedFiles = 1000000
+
+// GroupRecommendations holds sizing recommendations of opcache directives for group of nodes
+type GroupRecommendations struct {
+	ClusterName     string
+	GroupName       string
+	NodesCount      int // nodes with pulled statistics taken into account
+	Recommendations []Recommendation
+}
+
+// Recommendation describes proposed value of single directive with reasoning
+type Recommendation struct {
+	Directive        string
+	Unit             string
+	CurrentValue     int
+	RecommendedValue int
+	ChangeRequired   bool
+	Reasons          []string
+}
+
+// groupUsage holds maximum observed usage across nodes of group
+type groupUsage struct {
+	nodesCount                    int
+	maxAcceleratedFiles           int
+	hashSize                      int
+	usedKeys                      int
+	scripts                       int
+	hashRestarts                  int
+	cacheFull                     bool
+	memoryConsumption             int
+	usedMemory                    int
+	wastedMemory                  int
+	maxWastedPercentage           float64
+	oomRestarts                   int
+	internedStringsBuffer         int
+	internedStringsUsedMemory     int
+	internedStringsBufferSize     int
+	internedStringsHighUsageNodes int
+}
+
+// RecommendSizing proposes opcache.max_accelerated_files, opcache.memory_consumption and
+// opcache.interned_strings_buffer for every group from observed usage of its nodes
+func RecommendSizing(statuses observer.ClustersOpcacheStatuses) []GroupRecommendations {
+	groupRecommendations := []GroupRecommendations{}
+
+	for clusterName, groups := range statuses {
+		for groupName := range groups {
+			nodes, err := SelectNodes(statuses, clusterName, groupName, "")
+			if err != nil {
+				continue
+			}
+
+			usage := collectGroupUsage(nodes)
+			if usage.nodesCount == 0 {
+				continue
+			}
+
+			groupRecommendations = append(groupRecommendations, GroupRecommendations{
+				ClusterName: clusterName,
+				GroupName:   groupName,
+				NodesCount:  usage.nodesCount,
+				Recommendations: []Recommendation{
+					recommendMaxAcceleratedFiles(usage),
+					recommendMemoryConsumption(usage),
+					recommendInternedStringsBuffer(usage),
+				},
+			})
+		}
+	}
+
+	sort.Slice(groupRecommendations, func(i, j int) bool {
+		if groupRecommendations[i].ClusterName != groupRecommendations[j].ClusterName {
+			return groupRecommendations[i].ClusterName < groupRecommendations[j].ClusterName
+		}
+
+		return groupRecommendations[i].GroupName < groupRecommendations[j].GroupName
+	})
+
+	return groupRecommendations
+}
+
+func collectGroupUsage(nodes []Node) groupUsage {
+	usage := groupUsage{}
+
+	for _, node := range nodes {
+		status := node.Status
+
+		// usage of disabled node says nothing about required limits
+		if status.Configuration == nil || status.State == observer.NodeStateDisabled {
+			continue
+		}
+
+		usage.nodesCount++
+
+		usage.maxAcceleratedFiles = maxInt(usage.maxAcceleratedFiles, status.Keys.Total)
+		usage.hashSize = maxInt(usage.hashSize, status.Keys.TotalPrime)
+		usage.usedKeys = maxInt(usage.usedKeys, status.Keys.UsedKeys)
+		usage.scripts = maxInt(usage.scripts, maxInt(len(status.Scripts), status.Keys.UsedScripts))
+		usage.hashRestarts += status.Restarts.HashCount
+		usage.cacheFull = usage.cacheFull || status.CacheFull
+
+		usage.memoryConsumption = maxInt(usage.memoryConsumption, status.Memory.Total)
+		usage.usedMemory = maxInt(usage.usedMemory, status.Memory.Used)
+		usage.wastedMemory = maxInt(usage.wastedMemory, status.Memory.Wasted)
+		usage.maxWastedPercentage = math.Max(usage.maxWastedPercentage, status.Memory.MaxWastedPercentage)
+		usage.oomRestarts += status.Restarts.OutOfMemoryCount
+
+		usage.internedStringsBuffer = maxInt(usage.internedStringsBuffer, status.InternedStingsMemory.Total)
+		usage.internedStringsUsedMemory = maxInt(usage.internedStringsUsedMemory, status.InternedStingsMemory.UsedMemory)
+		usage.internedStringsBufferSize = maxInt(usage.internedStringsBufferSize, status.InternedStingsMemory.BufferSize)
+
+		if status.InternedStingsMemory.BufferSize > 0 &&
+			float64(status.InternedStingsMemory.UsedMemory) >= float64(status.InternedStingsMemory.BufferSize)*highUsageRatio {
+			usage.internedStringsHighUsageNodes++
+		}
+	}
+
+	return usage
+}
+
+func recommendMaxAcceleratedFiles(usage groupUsage) Recommendation {
+	recommendation := Recommendation{
+		Directive:    "opcache.max_accelerated_files",
+		Unit:         "files",
+		CurrentValue: usage.maxAcceleratedFiles,
+		Reasons:      []string{},
+	}
+
+	// keys include aliases of scripts, so real demand is the biggest of two
+	demand := maxInt(usage.usedKeys, usage.scripts)
+	wanted := int(math.Ceil(float64(demand) * keysHeadroom))
+	wanted = maxInt(wanted, minMaxAcceleratedFiles)
+	wanted = minInt(wanted, maxMaxAcceleratedFiles)
+
+	// PHP rounds value up to the prime, so recommend prime itself
+	recommendedHashSize := observer.AcceleratorHashSize(wanted)
+	currentHashSize := usage.hashSize
+	if currentHashSize == 0 {
+		currentHashSize = observer.AcceleratorHashSize(usage.maxAcceleratedFiles)
+	}
+
+	recommendation.Reasons = append(
+		recommendation.Reasons,
+		fmt.Sprintf(
+			"Up to %d keys and %d scripts cached on node, hash table of current configuration holds %d keys",
+			usage.usedKeys,
+			usage.scripts,
+			currentHashSize,
+		),
+	)
+
+	if usage.cacheFull {
+		recommendation.Reasons = append(recommendation.Reasons, "Cache is full on some nodes, new scripts are not cached")
+	}
+
+	if usage.hashRestarts > 0 {
+		recommendation.Reasons = append(
+			recommendation.Reasons,
+			fmt.Sprintf("%d restarts caused by hash table overflow", usage.hashRestarts),
+		)
+	}
+
+	if recommendedHashSize > currentHashSize {
+		recommendation.ChangeRequired = true
+		// prime above maximum of directive ignored by PHP, while maximum itself rounded up to that prime
+		recommendation.RecommendedValue = minInt(recommendedHashSize, maxMaxAcceleratedFiles)
+		recommendation.Reasons = append(
+			recommendation.Reasons,
+			fmt.Sprintf(
+				"%d keys required with %.0f%% headroom, PHP will round value up to prime %d",
+				wanted,
+				(keysHeadroom-1)*100,
+				recommendedHashSize,
+			),
+		)
+	} else {
+		recommendation.RecommendedValue = usage.maxAcceleratedFiles
+		recommendation.Reasons = append(recommendation.Reasons, "Current value is sufficient")
+	}
+
+	return recommendation
+}
+
+func recommendMemoryConsumption(usage groupUsage) Recommendation {
+	currentMegabytes := usage.memoryConsumption / bytesInMegabyte
+
+	recommendation := Recommendation{
+		Directive:    "opcache.memory_consumption",
+		Unit:         "MB",
+		CurrentValue: currentMegabytes,
+		Reasons:      []string{},
+	}
+
+	// memory must hold used memory with headroom and tolerated wasted memory
+	toleratedWasted := float64(usage.usedMemory) * usage.maxWastedPercentage
+	wanted := float64(usage.usedMemory)*memoryHeadroom + toleratedWasted
+	wantedMegabytes := int(math.Ceil(wanted / bytesInMegabyte))
+
+	// round up to 32 MB
+	wantedMegabytes = int(math.Ceil(float64(wantedMegabytes)/32) * 32)
+
+	recommendation.Reasons = append(
+		recommendation.Reasons,
+		fmt.Sprintf(
+			"Up to %d MB used and %d MB wasted on node, %d MB configured",
+			usage.usedMemory/bytesInMegabyte,
+			usage.wastedMemory/bytesInMegabyte,
+			currentMegabytes,
+		),
+	)
+
+	if usage.oomRestarts > 0 {
+		recommendation.Reasons = append(
+			recommendation.Reasons,
+			fmt.Sprintf("%d restarts caused by out of memory", usage.oomRestarts),
+		)
+
+		// memory was exhausted, so observed usage is lower than real demand
+		wantedMegabytes = maxInt(wantedMegabytes, currentMegabytes*2)
+	}
+
+	if wantedMegabytes > currentMegabytes {
+		recommendation.ChangeRequired = true
+		recommendation.RecommendedValue = wantedMegabytes
+		recommendation.Reasons = append(
+			recommendation.Reasons,
+			fmt.Sprintf(
+				"%d MB required to hold used memory with %.0f%% headroom and %.0f%% of tolerated wasted memory",
+				wantedMegabytes,
+				(memoryHeadroom-1)*100,
+				usage.maxWastedPercentage*100,
+			),
+		)
+	} else {
+		recommendation.RecommendedValue = currentMegabytes
+		recommendation.Reasons = append(recommendation.Reasons, "Current value is sufficient")
+	}
+
+	return recommendation
+}
+
+func recommendInternedStringsBuffer(usage groupUsage) Recommendation {
+	currentMegabytes := usage.internedStringsBuffer / bytesInMegabyte
+
+	recommendation := Recommendation{
+		Directive:    "opcache.interned_strings_buffer",
+		Unit:         "MB",
+		CurrentValue: currentMegabytes,
+		Reasons:      []string{},
+	}
+
+	recommendation.Reasons = append(
+		recommendation.Reasons,
+		fmt.Sprintf(
+			"Up to %d KB of %d KB interned strings buffer used on node",
+			usage.internedStringsUsedMemory/1024,
+			usage.internedStringsBufferSize/1024,
+		),
+	)
+
+	wantedMegabytes := int(math.Ceil(float64(usage.internedStringsUsedMemory) * internedStringsHeadroom / bytesInMegabyte))
+
+	if usage.internedStringsHighUsageNodes > 0 && wantedMegabytes <= currentMegabytes {
+		// buffer is full, so observed usage is lower than real demand
+		wantedMegabytes = currentMegabytes * 2
+	}
+
+	if usage.internedStringsHighUsageNodes > 0 {
+		recommendation.Reasons = append(
+			recommendation.Reasons,
+			fmt.Sprintf(
+				"Buffer is more than %.0f%% full on %d nodes, strings which do not fit are not interned and duplicated in every process",
+				highUsageRatio*100,
+				usage.internedStringsHighUsageNodes,
+			),
+		)
+	}
+
+	if wantedMegabytes > currentMegabytes {
+		recommendation.ChangeRequired = true
+		recommendation.RecommendedValue = wantedMegabytes
+		recommendation.Reasons = append(
+			recommendation.Reasons,
+			fmt.Sprintf(
+				"%d MB required to hold interned strings with %.0f%% headroom",
+				wantedMegabytes,
+				(internedStringsHeadroom-1)*100,
+			),
+		)
+	} else {
+		recommendation.RecommendedValue = currentMegabytes
+		recommendation.Reasons = append(recommendation.Reasons, "Current value is sufficient")
+	}
+
+	return recommendation
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
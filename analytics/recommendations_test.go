@@ -0,0 +1,233 @@
+package analytics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// buildNodeStatuses builds statuses of cluster "production" from pulled statuses of nodes,
+// node key is "group/host"
+func buildNodeStatuses(nodes map[string]observer.NodeOpcacheStatus) observer.ClustersOpcacheStatuses {
+	statuses := observer.ClustersOpcacheStatuses{"production": {}}
+
+	for node, status := range nodes {
+		groupName, hostName, _ := strings.Cut(node, "/")
+
+		if _, ok := statuses["production"][groupName]; !ok {
+			statuses["production"][groupName] = map[string]observer.NodeOpcacheStatus{}
+		}
+
+		if status.Configuration == nil && status.State != "" {
+			status.Configuration = map[string]interface{}{}
+		}
+
+		statuses["production"][groupName][hostName] = status
+	}
+
+	return statuses
+}
+
+func findRecommendation(t *testing.T, recommendations []Recommendation, directive string) Recommendation {
+	t.Helper()
+
+	for _, recommendation := range recommendations {
+		if recommendation.Directive == directive {
+			return recommendation
+		}
+	}
+
+	t.Fatalf("no recommendation of %s", directive)
+
+	return Recommendation{}
+}
+
+func TestRecommendSizing(t *testing.T) {
+	testCases := []struct {
+		name             string
+		status           observer.NodeOpcacheStatus
+		directive        string
+		changeRequired   bool
+		recommendedValue int
+	}{
+		{
+			name: "max accelerated files sufficient",
+			status: observer.NodeOpcacheStatus{
+				State: observer.NodeStateEnabled,
+				Keys:  observer.Keys{Total: 10000, TotalPrime: 16229, UsedKeys: 3000, UsedScripts: 2900},
+			},
+			directive:        "opcache.max_accelerated_files",
+			recommendedValue: 10000,
+		},
+		{
+			name: "max accelerated files rounded up to prime",
+			status: observer.NodeOpcacheStatus{
+				State: observer.NodeStateEnabled,
+				Keys:  observer.Keys{Total: 4000, TotalPrime: 7963, UsedKeys: 7900, UsedScripts: 7000},
+			},
+			directive:        "opcache.max_accelerated_files",
+			changeRequired:   true,
+			recommendedValue: 16229,
+		},
+		{
+			name: "max accelerated files clamped to maximum of directive",
+			status: observer.NodeOpcacheStatus{
+				State: observer.NodeStateEnabled,
+				Keys:  observer.Keys{Total: 500000, TotalPrime: 524521, UsedKeys: 900000, UsedScripts: 900000},
+			},
+			directive:        "opcache.max_accelerated_files",
+			changeRequired:   true,
+			recommendedValue: maxMaxAcceleratedFiles,
+		},
+		{
+			name: "memory consumption with headroom and tolerated wasted memory",
+			status: observer.NodeOpcacheStatus{
+				State:  observer.NodeStateEnabled,
+				Memory: observer.Memory{Total: 128 * bytesInMegabyte, Used: 100 * bytesInMegabyte, MaxWastedPercentage: 0.05},
+			},
+			directive:        "opcache.memory_consumption",
+			changeRequired:   true,
+			recommendedValue: 160,
+		},
+		{
+			name: "memory consumption sufficient",
+			status: observer.NodeOpcacheStatus{
+				State:  observer.NodeStateEnabled,
+				Memory: observer.Memory{Total: 256 * bytesInMegabyte, Used: 100 * bytesInMegabyte, MaxWastedPercentage: 0.05},
+			},
+			directive:        "opcache.memory_consumption",
+			recommendedValue: 256,
+		},
+		{
+			name: "memory consumption doubled after out of memory restarts",
+			status: observer.NodeOpcacheStatus{
+				State:    observer.NodeStateEnabled,
+				Memory:   observer.Memory{Total: 128 * bytesInMegabyte, Used: 20 * bytesInMegabyte},
+				Restarts: observer.Restarts{OutOfMemoryCount: 3},
+			},
+			directive:        "opcache.memory_consumption",
+			changeRequired:   true,
+			recommendedValue: 256,
+		},
+		{
+			name: "interned strings buffer with headroom",
+			status: observer.NodeOpcacheStatus{
+				State: observer.NodeStateEnabled,
+				InternedStingsMemory: observer.InternedStingsMemory{
+					Total:      8 * bytesInMegabyte,
+					BufferSize: 8 * bytesInMegabyte,
+					UsedMemory: 7*bytesInMegabyte + bytesInMegabyte/2,
+				},
+			},
+			directive:        "opcache.interned_strings_buffer",
+			changeRequired:   true,
+			recommendedValue: 12,
+		},
+		{
+			name: "interned strings buffer doubled when full",
+			status: observer.NodeOpcacheStatus{
+				State: observer.NodeStateEnabled,
+				InternedStingsMemory: observer.InternedStingsMemory{
+					Total:      16 * bytesInMegabyte,
+					BufferSize: 4 * bytesInMegabyte,
+					UsedMemory: 4*bytesInMegabyte - 100*1024,
+				},
+			},
+			directive:        "opcache.interned_strings_buffer",
+			changeRequired:   true,
+			recommendedValue: 32,
+		},
+		{
+			name: "interned strings buffer sufficient",
+			status: observer.NodeOpcacheStatus{
+				State: observer.NodeStateEnabled,
+				InternedStingsMemory: observer.InternedStingsMemory{
+					Total:      16 * bytesInMegabyte,
+					BufferSize: 16 * bytesInMegabyte,
+					UsedMemory: 2 * bytesInMegabyte,
+				},
+			},
+			directive:        "opcache.interned_strings_buffer",
+			recommendedValue: 16,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			groupRecommendations := RecommendSizing(buildNodeStatuses(map[string]observer.NodeOpcacheStatus{
+				"web/web1": testCase.status,
+			}))
+
+			if len(groupRecommendations) != 1 {
+				t.Fatalf("expected recommendations of single group, got %d", len(groupRecommendations))
+			}
+
+			recommendation := findRecommendation(t, groupRecommendations[0].Recommendations, testCase.directive)
+
+			if recommendation.ChangeRequired != testCase.changeRequired || recommendation.RecommendedValue != testCase.recommendedValue {
+				t.Errorf(
+					"got change required %v with value %d, want %v with value %d: %v",
+					recommendation.ChangeRequired,
+					recommendation.RecommendedValue,
+					testCase.changeRequired,
+					testCase.recommendedValue,
+					recommendation.Reasons,
+				)
+			}
+		})
+	}
+}
+
+func TestRecommendSizingSkipsDisabledNodes(t *testing.T) {
+	statuses := buildNodeStatuses(map[string]observer.NodeOpcacheStatus{
+		"web/web1": {
+			State:  observer.NodeStateEnabled,
+			Keys:   observer.Keys{Total: 10000, TotalPrime: 16229, UsedKeys: 3000},
+			Memory: observer.Memory{Total: 128 * bytesInMegabyte, Used: 20 * bytesInMegabyte},
+		},
+		// usage of disabled node ignored
+		"web/web2": {
+			State:    observer.NodeStateDisabled,
+			Keys:     observer.Keys{Total: 10000, TotalPrime: 16229, UsedKeys: 16000},
+			Restarts: observer.Restarts{OutOfMemoryCount: 10},
+		},
+		// statistics not pulled yet
+		"web/web3":   {},
+		"api/api1":   {State: observer.NodeStateDisabled},
+		"cron/cron1": {},
+	})
+
+	groupRecommendations := RecommendSizing(statuses)
+
+	if len(groupRecommendations) != 1 || groupRecommendations[0].GroupName != "web" || groupRecommendations[0].NodesCount != 1 {
+		t.Fatalf("got %+v", groupRecommendations)
+	}
+
+	for _, recommendation := range groupRecommendations[0].Recommendations {
+		if recommendation.ChangeRequired {
+			t.Errorf("%s: change required by disabled node: %v", recommendation.Directive, recommendation.Reasons)
+		}
+	}
+}
+
+func TestRecommendSizingOrder(t *testing.T) {
+	statuses := buildNodeStatuses(map[string]observer.NodeOpcacheStatus{
+		"web/web1":   {State: observer.NodeStateEnabled},
+		"api/api1":   {State: observer.NodeStateEnabled},
+		"cron/cron1": {State: observer.NodeStateEnabled},
+	})
+	statuses["staging"] = map[string]map[string]observer.NodeOpcacheStatus{
+		"api": {"api1": {State: observer.NodeStateEnabled, Configuration: map[string]interface{}{}}},
+	}
+
+	groups := []string{}
+	for _, recommendations := range RecommendSizing(statuses) {
+		groups = append(groups, recommendations.ClusterName+"/"+recommendations.GroupName)
+	}
+
+	if expected := []string{"production/api", "production/cron", "production/web", "staging/api"}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("got %v, want %v", groups, expected)
+	}
+}
@@ -0,0 +1,331 @@
+package analytics
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+const DefaultScriptsLimit = 50
+const MaxScriptsLimit = 1000
+
+// Supported fields of script sorting
+const (
+	ScriptSortByMemory   = "memory"
+	ScriptSortByHits     = "hits"
+	ScriptSortByAge      = "age"
+	ScriptSortByLastUsed = "lastUsed"
+	ScriptSortByPath     = "path"
+)
+
+// ErrScopeNotFound returned when requested cluster, group or host not configured
+var ErrScopeNotFound = errors.New("Requested cluster, group or host not found")
+
+// ScriptQuery defines scope, filtering, sorting and pagination of scripts
+type ScriptQuery struct {
+	ClusterName string
+	GroupName   string // optional, all groups of cluster when empty
+	HostName    string // optional, all hosts of group when empty
+	PathPrefix  string
+	PathRegex   *regexp.Regexp
+	SortBy      string
+	Ascending   bool
+	// Preloaded selects only preloaded scripts when true, only regular cached scripts when false
+	Preloaded *bool
+	// Aggregate merges same script from different nodes into one item
+	Aggregate bool
+	Limit     int
+	Cursor    string
+}
+
+// ScriptItem represents script on single node or script aggregated across nodes
+type ScriptItem struct {
+	Path              string
+	GroupName         string // empty for aggregated item
+	HostName          string // empty for aggregated item
+	Hits              int    // sum of hits across nodes for aggregated item
+	Memory            int    // sum of memory across nodes for aggregated item
+	CreateTimestamp   int64  // earliest across nodes for aggregated item
+	LastUsedTimestamp int64  // latest across nodes for aggregated item
+	AgeSeconds        int64
+	NodesCount        int
+	// Script preloaded by opcache.preload, on any node for aggregated item
+	Preloaded bool
+}
+
+// ScriptPage represents single page of queried scripts
+type ScriptPage struct {
+	Items      []ScriptItem
+	Total      int
+	NextCursor string // empty on last page
+}
+
+type scriptCursor struct {
+	Value int64  `json:"v"`
+	Key   string `json:"k"`
+}
+
+// QueryScripts selects scripts from pulled opcache statuses
+func QueryScripts(
+	statuses observer.ClustersOpcacheStatuses,
+	query ScriptQuery,
+	now time.Time,
+) (*ScriptPage, error) {
+	switch query.SortBy {
+	case "":
+		query.SortBy = ScriptSortByMemory
+	case ScriptSortByMemory, ScriptSortByHits, ScriptSortByAge, ScriptSortByLastUsed, ScriptSortByPath:
+	default:
+		return nil, fmt.Errorf("Unknown sort field '%s'", query.SortBy)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultScriptsLimit
+	} else if query.Limit > MaxScriptsLimit {
+		query.Limit = MaxScriptsLimit
+	}
+
+	nodes, err := SelectNodes(statuses, query.ClusterName, query.GroupName, query.HostName)
+	if err != nil {
+		return nil, err
+	}
+
+	items := collectScriptItems(nodes, query, now)
+
+	sort.Slice(items, func(i, j int) bool {
+		return compareScriptItems(items[i], items[j], query) < 0
+	})
+
+	// skip items up to cursor
+	startIndex := 0
+	if query.Cursor != "" {
+		cursor, err := decodeScriptCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		startIndex = sort.Search(len(items), func(i int) bool {
+			return compareToCursor(items[i], cursor, query) > 0
+		})
+	}
+
+	page := &ScriptPage{
+		Items: []ScriptItem{},
+		Total: len(items),
+	}
+
+	endIndex := startIndex + query.Limit
+	if endIndex > len(items) {
+		endIndex = len(items)
+	}
+
+	page.Items = append(page.Items, items[startIndex:endIndex]...)
+
+	if endIndex < len(items) {
+		lastItem := items[endIndex-1]
+		page.NextCursor = encodeScriptCursor(scriptCursor{
+			Value: scriptSortValue(lastItem, query.SortBy),
+			Key:   scriptItemKey(lastItem),
+		})
+	}
+
+	return page, nil
+}
+
+func collectScriptItems(nodes []Node, query ScriptQuery, now time.Time) []ScriptItem {
+	items := []ScriptItem{}
+	aggregatedItems := map[string]*ScriptItem{}
+
+	for _, node := range nodes {
+		for path, script := range nodeScripts(node.Status) {
+			if query.PathPrefix != "" && !strings.HasPrefix(path, query.PathPrefix) {
+				continue
+			}
+
+			if query.PathRegex != nil && !query.PathRegex.MatchString(path) {
+				continue
+			}
+
+			if query.Preloaded != nil && *query.Preloaded != script.preloaded {
+				continue
+			}
+
+			if !query.Aggregate {
+				items = append(items, ScriptItem{
+					Path:              path,
+					GroupName:         node.GroupName,
+					HostName:          node.HostName,
+					Hits:              script.Hits,
+					Memory:            script.Memory,
+					CreateTimestamp:   script.CreateTimestamp,
+					LastUsedTimestamp: script.LastUsedTimestamp,
+					AgeSeconds:        scriptAgeSeconds(script.CreateTimestamp, now),
+					NodesCount:        1,
+					Preloaded:         script.preloaded,
+				})
+
+				continue
+			}
+
+			aggregatedItem, ok := aggregatedItems[path]
+			if !ok {
+				aggregatedItems[path] = &ScriptItem{
+					Path:              path,
+					Hits:              script.Hits,
+					Memory:            script.Memory,
+					CreateTimestamp:   script.CreateTimestamp,
+					LastUsedTimestamp: script.LastUsedTimestamp,
+					NodesCount:        1,
+					Preloaded:         script.preloaded,
+				}
+
+				continue
+			}
+
+			aggregatedItem.Hits += script.Hits
+			aggregatedItem.Memory += script.Memory
+			aggregatedItem.NodesCount++
+			aggregatedItem.Preloaded = aggregatedItem.Preloaded || script.preloaded
+
+			if script.CreateTimestamp < aggregatedItem.CreateTimestamp {
+				aggregatedItem.CreateTimestamp = script.CreateTimestamp
+			}
+
+			if script.LastUsedTimestamp > aggregatedItem.LastUsedTimestamp {
+				aggregatedItem.LastUsedTimestamp = script.LastUsedTimestamp
+			}
+		}
+	}
+
+	for _, aggregatedItem := range aggregatedItems {
+		aggregatedItem.AgeSeconds = scriptAgeSeconds(aggregatedItem.CreateTimestamp, now)
+		items = append(items, *aggregatedItem)
+	}
+
+	return items
+}
+
+type nodeScript struct {
+	observer.Script
+	preloaded bool
+}
+
+// nodeScripts returns cached scripts of node together with preloaded ones,
+// which may be absent in list of cached scripts
+func nodeScripts(status observer.NodeOpcacheStatus) map[string]nodeScript {
+	scripts := make(map[string]nodeScript, len(status.Scripts))
+
+	for path, script := range status.Scripts {
+		scripts[path] = nodeScript{Script: script}
+	}
+
+	if status.Preload != nil {
+		for _, path := range status.Preload.Scripts {
+			script := scripts[path]
+			script.preloaded = true
+			scripts[path] = script
+		}
+	}
+
+	return scripts
+}
+
+// scriptAgeSeconds returns zero age for preloaded scripts without cache timestamps
+func scriptAgeSeconds(createTimestamp int64, now time.Time) int64 {
+	if createTimestamp == 0 {
+		return 0
+	}
+
+	return now.Unix() - createTimestamp
+}
+
+func scriptSortValue(item ScriptItem, sortBy string) int64 {
+	switch sortBy {
+	case ScriptSortByHits:
+		return int64(item.Hits)
+	case ScriptSortByAge:
+		// age derived from current time changes between page requests, so order by creation time
+		// inverted: older script has lower timestamp but greater age
+		if item.CreateTimestamp == 0 {
+			// preloaded scripts without cache timestamps shown with zero age
+			return math.MinInt64
+		}
+
+		return -item.CreateTimestamp
+	case ScriptSortByLastUsed:
+		return item.LastUsedTimestamp
+	case ScriptSortByPath:
+		return 0
+	default:
+		return int64(item.Memory)
+	}
+}
+
+// scriptItemKey uniquely identifies item in result set
+func scriptItemKey(item ScriptItem) string {
+	return item.Path + "\x00" + item.GroupName + "\x00" + item.HostName
+}
+
+func compareScriptItems(a ScriptItem, b ScriptItem, query ScriptQuery) int {
+	return compareToCursor(
+		a,
+		scriptCursor{Value: scriptSortValue(b, query.SortBy), Key: scriptItemKey(b)},
+		query,
+	)
+}
+
+// compareToCursor returns negative value if item goes before cursor in sorting order
+func compareToCursor(item ScriptItem, cursor scriptCursor, query ScriptQuery) int {
+	value := scriptSortValue(item, query.SortBy)
+
+	if value != cursor.Value {
+		result := 1
+		if value < cursor.Value {
+			result = -1
+		}
+
+		if !query.Ascending {
+			result = -result
+		}
+
+		return result
+	}
+
+	// sort by key always ascending for stable pagination,
+	// except when path itself is sort field
+	result := strings.Compare(scriptItemKey(item), cursor.Key)
+	if query.SortBy == ScriptSortByPath && !query.Ascending {
+		result = -result
+	}
+
+	return result
+}
+
+func encodeScriptCursor(cursor scriptCursor) string {
+	cursorJSON, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func decodeScriptCursor(encodedCursor string) (scriptCursor, error) {
+	cursor := scriptCursor{}
+
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(encodedCursor)
+	if err != nil {
+		return cursor, errors.New("Invalid cursor")
+	}
+
+	if err := json.Unmarshal(cursorJSON, &cursor); err != nil {
+		return cursor, errors.New("Invalid cursor")
+	}
+
+	return cursor, nil
+}
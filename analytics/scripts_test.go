@@ -0,0 +1,351 @@
+package analytics
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+var testNow = time.Unix(1760860800, 0)
+
+// buildStatuses builds statuses of single cluster "production" from scripts of nodes,
+// node key is "group/host"
+func buildStatuses(nodesScripts map[string]map[string]observer.Script) observer.ClustersOpcacheStatuses {
+	statuses := observer.ClustersOpcacheStatuses{"production": {}}
+
+	for node, scripts := range nodesScripts {
+		groupName, hostName, _ := strings.Cut(node, "/")
+
+		if _, ok := statuses["production"][groupName]; !ok {
+			statuses["production"][groupName] = map[string]observer.NodeOpcacheStatus{}
+		}
+
+		statuses["production"][groupName][hostName] = observer.NodeOpcacheStatus{
+			State:   observer.NodeStateEnabled,
+			Scripts: scripts,
+		}
+	}
+
+	return statuses
+}
+
+// queryAllPages follows cursors until last page, calling beforePage before every page after first
+func queryAllPages(
+	t *testing.T,
+	statuses observer.ClustersOpcacheStatuses,
+	query ScriptQuery,
+	beforePage func(pageNumber int) (observer.ClustersOpcacheStatuses, time.Time),
+) []string {
+	t.Helper()
+
+	keys := []string{}
+	now := testNow
+
+	for pageNumber := 0; pageNumber < 100; pageNumber++ {
+		if pageNumber > 0 && beforePage != nil {
+			statuses, now = beforePage(pageNumber)
+		}
+
+		page, err := QueryScripts(statuses, query, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, item := range page.Items {
+			keys = append(keys, item.Path+"@"+item.HostName)
+		}
+
+		if page.NextCursor == "" {
+			return keys
+		}
+
+		query.Cursor = page.NextCursor
+	}
+
+	t.Fatal("pagination does not end")
+
+	return nil
+}
+
+func TestQueryScriptsPaginationWithTies(t *testing.T) {
+	// all scripts have same memory, hits and creation time
+	scripts := map[string]observer.Script{}
+	for i := 0; i < 7; i++ {
+		scripts[fmt.Sprintf("/var/www/src/%d.php", i)] = observer.Script{Memory: 1024, Hits: 5, CreateTimestamp: 1760850000}
+	}
+
+	statuses := buildStatuses(map[string]map[string]observer.Script{"web/web1": scripts, "web/web2": scripts})
+
+	for _, sortBy := range []string{ScriptSortByMemory, ScriptSortByHits, ScriptSortByAge, ScriptSortByPath} {
+		for _, ascending := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s ascending %v", sortBy, ascending), func(t *testing.T) {
+				query := ScriptQuery{ClusterName: "production", SortBy: sortBy, Ascending: ascending, Limit: 3}
+
+				keys := queryAllPages(t, statuses, query, nil)
+
+				seen := map[string]bool{}
+				for _, key := range keys {
+					if seen[key] {
+						t.Errorf("%s returned twice", key)
+					}
+					seen[key] = true
+				}
+
+				if len(keys) != 14 {
+					t.Errorf("expected 14 scripts, got %d: %v", len(keys), keys)
+				}
+			})
+		}
+	}
+}
+
+func TestQueryScriptsSortByAge(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{
+		"web/web1": {
+			"/var/www/old.php":    {CreateTimestamp: 1760800000},
+			"/var/www/new.php":    {CreateTimestamp: 1760860000},
+			"/var/www/middle.php": {CreateTimestamp: 1760830000},
+			"/var/www/twin.php":   {CreateTimestamp: 1760830000},
+		},
+	})
+	statuses["production"]["web"]["web1"] = observer.NodeOpcacheStatus{
+		Scripts: statuses["production"]["web"]["web1"].Scripts,
+		// preloaded script without cache timestamps
+		Preload: &observer.NodePreloadStatus{Scripts: []string{"/var/www/preload.php"}},
+	}
+
+	page, err := QueryScripts(statuses, ScriptQuery{ClusterName: "production", SortBy: ScriptSortByAge}, testNow)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	paths := []string{}
+	ages := []int64{}
+	for _, item := range page.Items {
+		paths = append(paths, item.Path)
+		ages = append(ages, item.AgeSeconds)
+	}
+
+	// oldest first, scripts of same age ordered by path
+	expectedPaths := []string{"/var/www/old.php", "/var/www/middle.php", "/var/www/twin.php", "/var/www/new.php", "/var/www/preload.php"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("paths: got %v, want %v", paths, expectedPaths)
+	}
+
+	expectedAges := []int64{60800, 30800, 30800, 800, 0}
+	if !reflect.DeepEqual(ages, expectedAges) {
+		t.Errorf("ages: got %v, want %v", ages, expectedAges)
+	}
+}
+
+func TestQueryScriptsAgeCursorStableOverTime(t *testing.T) {
+	scripts := map[string]observer.Script{}
+	for i := 0; i < 10; i++ {
+		scripts[fmt.Sprintf("/var/www/%d.php", i)] = observer.Script{CreateTimestamp: 1760850000 + int64(i%4)*60}
+	}
+
+	statuses := buildStatuses(map[string]map[string]observer.Script{"web/web1": scripts})
+	query := ScriptQuery{ClusterName: "production", SortBy: ScriptSortByAge, Limit: 3}
+
+	expected := queryAllPages(t, statuses, query, nil)
+
+	// every next page requested hour later, so age of every script grows
+	keys := queryAllPages(t, statuses, query, func(pageNumber int) (observer.ClustersOpcacheStatuses, time.Time) {
+		return statuses, testNow.Add(time.Duration(pageNumber) * time.Hour)
+	})
+
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("got %v, want %v", keys, expected)
+	}
+}
+
+func TestQueryScriptsCursorWhenScriptsChange(t *testing.T) {
+	baseScripts := func() map[string]observer.Script {
+		return map[string]observer.Script{
+			"/a.php": {Memory: 600},
+			"/b.php": {Memory: 500},
+			"/c.php": {Memory: 400},
+			"/d.php": {Memory: 300},
+			"/e.php": {Memory: 200},
+			"/f.php": {Memory: 100},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		change   func(scripts map[string]observer.Script)
+		expected []string
+	}{
+		{
+			name:     "script of previous page removed",
+			change:   func(scripts map[string]observer.Script) { delete(scripts, "/a.php") },
+			expected: []string{"/a.php", "/b.php", "/c.php", "/d.php", "/e.php", "/f.php"},
+		},
+		{
+			name:     "last script of previous page removed",
+			change:   func(scripts map[string]observer.Script) { delete(scripts, "/b.php") },
+			expected: []string{"/a.php", "/b.php", "/c.php", "/d.php", "/e.php", "/f.php"},
+		},
+		{
+			name:     "next script removed",
+			change:   func(scripts map[string]observer.Script) { delete(scripts, "/c.php") },
+			expected: []string{"/a.php", "/b.php", "/d.php", "/e.php", "/f.php"},
+		},
+		{
+			name:     "script added before cursor",
+			change:   func(scripts map[string]observer.Script) { scripts["/z.php"] = observer.Script{Memory: 1000} },
+			expected: []string{"/a.php", "/b.php", "/c.php", "/d.php", "/e.php", "/f.php"},
+		},
+		{
+			name:     "script added after cursor",
+			change:   func(scripts map[string]observer.Script) { scripts["/z.php"] = observer.Script{Memory: 350} },
+			expected: []string{"/a.php", "/b.php", "/c.php", "/z.php", "/d.php", "/e.php", "/f.php"},
+		},
+		{
+			name:     "script with same value as cursor added after it by key",
+			change:   func(scripts map[string]observer.Script) { scripts["/bb.php"] = observer.Script{Memory: 500} },
+			expected: []string{"/a.php", "/b.php", "/bb.php", "/c.php", "/d.php", "/e.php", "/f.php"},
+		},
+		{
+			name:     "script with same value as cursor added before it by key",
+			change:   func(scripts map[string]observer.Script) { scripts["/ab.php"] = observer.Script{Memory: 500} },
+			expected: []string{"/a.php", "/b.php", "/c.php", "/d.php", "/e.php", "/f.php"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			scripts := baseScripts()
+			statuses := buildStatuses(map[string]map[string]observer.Script{"web/web1": scripts})
+			query := ScriptQuery{ClusterName: "production", SortBy: ScriptSortByMemory, Limit: 2}
+
+			keys := queryAllPages(t, statuses, query, func(pageNumber int) (observer.ClustersOpcacheStatuses, time.Time) {
+				if pageNumber == 1 {
+					testCase.change(scripts)
+				}
+
+				return statuses, testNow
+			})
+
+			paths := []string{}
+			for _, key := range keys {
+				paths = append(paths, key[:len(key)-len("@web1")])
+			}
+
+			if !reflect.DeepEqual(paths, testCase.expected) {
+				t.Errorf("got %v, want %v", paths, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestQueryScriptsAggregate(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{
+		"web/web1": {
+			"/index.php": {Hits: 10, Memory: 100, CreateTimestamp: 1760850000, LastUsedTimestamp: 1760860000},
+		},
+		"api/api1": {
+			"/index.php": {Hits: 5, Memory: 100, CreateTimestamp: 1760840000, LastUsedTimestamp: 1760860500},
+			"/api.php":   {Hits: 1, Memory: 50, CreateTimestamp: 1760840000, LastUsedTimestamp: 1760840000},
+		},
+	})
+
+	page, err := QueryScripts(statuses, ScriptQuery{ClusterName: "production", Aggregate: true}, testNow)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ScriptItem{
+		{
+			Path:              "/index.php",
+			Hits:              15,
+			Memory:            200,
+			CreateTimestamp:   1760840000,
+			LastUsedTimestamp: 1760860500,
+			AgeSeconds:        20800,
+			NodesCount:        2,
+		},
+		{
+			Path:              "/api.php",
+			Hits:              1,
+			Memory:            50,
+			CreateTimestamp:   1760840000,
+			LastUsedTimestamp: 1760840000,
+			AgeSeconds:        20800,
+			NodesCount:        1,
+		},
+	}
+
+	if !reflect.DeepEqual(page.Items, expected) || page.Total != 2 {
+		t.Errorf("got %+v, want %+v", page.Items, expected)
+	}
+}
+
+func TestQueryScriptsFilters(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{
+		"web/web1": {
+			"/var/www/src/Kernel.php":      {Memory: 3},
+			"/var/www/src/Controller.php":  {Memory: 2},
+			"/var/www/vendor/autoload.php": {Memory: 1},
+		},
+	})
+
+	testCases := []struct {
+		name     string
+		query    ScriptQuery
+		expected []string
+	}{
+		{
+			name:     "path prefix",
+			query:    ScriptQuery{PathPrefix: "/var/www/src/"},
+			expected: []string{"/var/www/src/Kernel.php@web1", "/var/www/src/Controller.php@web1"},
+		},
+		{
+			name:     "path regex",
+			query:    ScriptQuery{PathRegex: regexp.MustCompile(`/vendor/`)},
+			expected: []string{"/var/www/vendor/autoload.php@web1"},
+		},
+		{
+			name:     "path descending",
+			query:    ScriptQuery{SortBy: ScriptSortByPath},
+			expected: []string{"/var/www/vendor/autoload.php@web1", "/var/www/src/Kernel.php@web1", "/var/www/src/Controller.php@web1"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.query.ClusterName = "production"
+
+			keys := queryAllPages(t, statuses, testCase.query, nil)
+			if !reflect.DeepEqual(keys, testCase.expected) {
+				t.Errorf("got %v, want %v", keys, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestQueryScriptsErrors(t *testing.T) {
+	statuses := buildStatuses(map[string]map[string]observer.Script{"web/web1": {"/index.php": {}}})
+
+	testCases := map[string]ScriptQuery{
+		"unknown sort field": {ClusterName: "production", SortBy: "size"},
+		"unknown cluster":    {ClusterName: "staging"},
+		"unknown group":      {ClusterName: "production", GroupName: "api"},
+		"unknown host":       {ClusterName: "production", GroupName: "web", HostName: "web9"},
+		"invalid cursor":     {ClusterName: "production", Cursor: "not a cursor"},
+		"cursor not json":    {ClusterName: "production", Cursor: "bm90IGpzb24"},
+	}
+
+	for name, query := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := QueryScripts(statuses, query, testNow); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+// Names of distributions across nodes
+const (
+	DistributionMemoryUsed   = "memoryUsed"
+	DistributionMemoryFree   = "memoryFree"
+	DistributionMemoryWasted = "memoryWasted"
+	DistributionHitRatio     = "hitRatio"
+	DistributionKeysUsed     = "keysUsed"
+	DistributionScripts      = "scripts"
+)
+
+// Summary holds statistics rolled up from nodes of group or cluster
+type Summary struct {
+	ClusterName string
+	GroupName   string // empty for cluster summary
+	NodesCount  int
+	// Nodes with pulled statistics, only they are summarized
+	ReportingNodesCount int
+	// Count of reporting nodes in every state, disabled nodes are not summarized
+	NodeStates     map[observer.NodeState]int
+	Memory         MemorySummary
+	KeyHits        KeyHitsSummary
+	Rates          RatesSummary
+	Keys           KeysSummary
+	CacheFullNodes []NodeRef
+	Restarts       RestartsSummary
+	Preload        PreloadSummary
+	Distributions  map[string]Distribution
+	// Summaries of groups, defined only in cluster summary
+	Groups map[string]Summary `json:",omitempty"`
+}
+
+type MemorySummary struct {
+	Total  int
+	Used   int
+	Free   int
+	Wasted int
+}
+
+type KeyHitsSummary struct {
+	Hits   int
+	Misses int
+	// Hits to all requests of scripts across nodes
+	HitRatio float64
+}
+
+// RatesSummary sums interval rates of nodes
+type RatesSummary struct {
+	HitsPerSecond   float64
+	MissesPerSecond float64
+	// Hits to all requests of scripts across nodes within last interval
+	HitRatio               float64
+	NewOutOfMemoryRestarts int
+	NewHashRestarts        int
+}
+
+type KeysSummary struct {
+	Total       int // sum of real hash table sizes
+	Used        int
+	Utilisation float64
+}
+
+type RestartsSummary struct {
+	OutOfMemoryCount int
+	HashCount        int
+	ManualCount      int
+}
+
+// PreloadSummary describes preloading on nodes with configured opcache.preload
+type PreloadSummary struct {
+	NodesCount        int
+	MemoryConsumption int // sum across nodes
+	// Share of used opcache memory of preloading nodes taken by preloaded code
+	MemoryRatio    float64
+	ScriptsCount   int // distinct preloaded scripts across nodes
+	FunctionsCount int // maximum across nodes
+	ClassesCount   int // maximum across nodes
+}
+
+// Distribution describes spread of value across nodes
+type Distribution struct {
+	Min  float64
+	Max  float64
+	Mean float64
+	P50  float64
+	P90  float64
+	P99  float64
+}
+
+// SummarizeCluster rolls up statistics of all nodes in cluster, including summary of every group
+func SummarizeCluster(statuses observer.ClustersOpcacheStatuses, clusterName string) (*Summary, error) {
+	nodes, err := SelectNodes(statuses, clusterName, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := summarizeNodes(clusterName, "", nodes)
+	summary.Groups = map[string]Summary{}
+
+	for groupName := range statuses[clusterName] {
+		groupSummary, err := SummarizeGroup(statuses, clusterName, groupName)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.Groups[groupName] = *groupSummary
+	}
+
+	return &summary, nil
+}
+
+// SummarizeGroup rolls up statistics of all nodes in group
+func SummarizeGroup(statuses observer.ClustersOpcacheStatuses, clusterName string, groupName string) (*Summary, error) {
+	if groupName == "" {
+		return nil, ErrScopeNotFound
+	}
+
+	nodes, err := SelectNodes(statuses, clusterName, groupName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := summarizeNodes(clusterName, groupName, nodes)
+
+	return &summary, nil
+}
+
+func summarizeNodes(clusterName string, groupName string, nodes []Node) Summary {
+	summary := Summary{
+		ClusterName:    clusterName,
+		GroupName:      groupName,
+		NodesCount:     len(nodes),
+		NodeStates:     map[observer.NodeState]int{},
+		CacheFullNodes: []NodeRef{},
+		Distributions:  map[string]Distribution{},
+	}
+
+	for _, nodeState := range observer.NodeStates {
+		summary.NodeStates[nodeState] = 0
+	}
+
+	distributionValues := map[string][]float64{}
+	preloadedScripts := map[string]bool{}
+	preloadingNodesUsedMemory := 0
+
+	for _, node := range nodes {
+		status := node.Status
+
+		// statistics not pulled yet
+		if status.Configuration == nil {
+			continue
+		}
+
+		summary.ReportingNodesCount++
+		summary.NodeStates[status.State]++
+
+		if status.State == observer.NodeStateDisabled {
+			continue
+		}
+
+		summary.Memory.Total += status.Memory.Total
+		summary.Memory.Used += status.Memory.Used
+		summary.Memory.Free += status.Memory.Free
+		summary.Memory.Wasted += status.Memory.Wasted
+
+		summary.KeyHits.Hits += status.KeyHits.Hits
+		summary.KeyHits.Misses += status.KeyHits.Misses
+
+		if status.Rates != nil {
+			summary.Rates.HitsPerSecond += status.Rates.HitsPerSecond
+			summary.Rates.MissesPerSecond += status.Rates.MissesPerSecond
+			summary.Rates.NewOutOfMemoryRestarts += status.Rates.NewOutOfMemoryRestarts
+			summary.Rates.NewHashRestarts += status.Rates.NewHashRestarts
+		}
+
+		summary.Keys.Total += status.Keys.TotalPrime
+		summary.Keys.Used += status.Keys.UsedKeys
+
+		summary.Restarts.OutOfMemoryCount += status.Restarts.OutOfMemoryCount
+		summary.Restarts.HashCount += status.Restarts.HashCount
+		summary.Restarts.ManualCount += status.Restarts.ManualCount
+
+		if status.Preload != nil {
+			summary.Preload.NodesCount++
+			summary.Preload.MemoryConsumption += status.Preload.MemoryConsumption
+			summary.Preload.FunctionsCount = maxInt(summary.Preload.FunctionsCount, status.Preload.FunctionsCount)
+			summary.Preload.ClassesCount = maxInt(summary.Preload.ClassesCount, status.Preload.ClassesCount)
+			preloadingNodesUsedMemory += status.Memory.Used
+
+			for _, path := range status.Preload.Scripts {
+				preloadedScripts[path] = true
+			}
+		}
+
+		if status.CacheFull {
+			summary.CacheFullNodes = append(summary.CacheFullNodes, NodeRef{node.GroupName, node.HostName})
+		}
+
+		distributionValues[DistributionMemoryUsed] = append(distributionValues[DistributionMemoryUsed], float64(status.Memory.Used))
+		distributionValues[DistributionMemoryFree] = append(distributionValues[DistributionMemoryFree], float64(status.Memory.Free))
+		distributionValues[DistributionMemoryWasted] = append(distributionValues[DistributionMemoryWasted], float64(status.Memory.Wasted))
+		distributionValues[DistributionHitRatio] = append(distributionValues[DistributionHitRatio], ratio(status.KeyHits.Hits, status.KeyHits.Hits+status.KeyHits.Misses))
+		distributionValues[DistributionKeysUsed] = append(distributionValues[DistributionKeysUsed], float64(status.Keys.UsedKeys))
+		distributionValues[DistributionScripts] = append(distributionValues[DistributionScripts], float64(status.Keys.UsedScripts))
+	}
+
+	summary.KeyHits.HitRatio = ratio(summary.KeyHits.Hits, summary.KeyHits.Hits+summary.KeyHits.Misses)
+	if requestsPerSecond := summary.Rates.HitsPerSecond + summary.Rates.MissesPerSecond; requestsPerSecond > 0 {
+		summary.Rates.HitRatio = summary.Rates.HitsPerSecond / requestsPerSecond
+	}
+
+	summary.Keys.Utilisation = ratio(summary.Keys.Used, summary.Keys.Total)
+
+	summary.Preload.ScriptsCount = len(preloadedScripts)
+	summary.Preload.MemoryRatio = ratio(summary.Preload.MemoryConsumption, preloadingNodesUsedMemory)
+
+	for distributionName, values := range distributionValues {
+		summary.Distributions[distributionName] = buildDistribution(values)
+	}
+
+	return summary
+}
+
+func buildDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+
+	sort.Float64s(values)
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+
+	return Distribution{
+		Min:  values[0],
+		Max:  values[len(values)-1],
+		Mean: sum / float64(len(values)),
+		P50:  percentile(values, 50),
+		P90:  percentile(values, 90),
+		P99:  percentile(values, 99),
+	}
+}
+
+// percentile calculates nearest-rank percentile of sorted values
+func percentile(sortedValues []float64, percent float64) float64 {
+	rank := int(math.Ceil(percent / 100 * float64(len(sortedValues))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sortedValues[rank-1]
+}
+
+func ratio(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) / float64(total)
+}
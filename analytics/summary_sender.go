@@ -0,0 +1,26 @@
+package analytics
+
+import "github.com/GoMetric/opcache-dashboard/observer"
+
+// SummarySenderInterface tracks rolled up statistics of groups and clusters
+type SummarySenderInterface interface {
+	SendSummary(summary Summary)
+}
+
+// SendSummaries rolls up statistics of every cluster and its groups and passes them to senders
+func SendSummaries(statuses observer.ClustersOpcacheStatuses, senders []SummarySenderInterface) {
+	for clusterName := range statuses {
+		clusterSummary, err := SummarizeCluster(statuses, clusterName)
+		if err != nil {
+			continue
+		}
+
+		for _, sender := range senders {
+			sender.SendSummary(*clusterSummary)
+
+			for _, groupSummary := range clusterSummary.Groups {
+				sender.SendSummary(groupSummary)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoMetric/opcache-dashboard/observer"
+)
+
+func TestSummarizeGroup(t *testing.T) {
+	statuses := buildNodeStatuses(map[string]observer.NodeOpcacheStatus{
+		"web/web1": {
+			State:    observer.NodeStateEnabled,
+			Memory:   observer.Memory{Total: 100, Used: 60, Free: 30, Wasted: 10},
+			KeyHits:  observer.KeyHits{Hits: 90, Misses: 10},
+			Keys:     observer.Keys{TotalPrime: 200, UsedKeys: 50, UsedScripts: 40},
+			Restarts: observer.Restarts{OutOfMemoryCount: 1, ManualCount: 2},
+			Rates:    &observer.Rates{HitsPerSecond: 30, MissesPerSecond: 10, NewOutOfMemoryRestarts: 1},
+			Preload: &observer.NodePreloadStatus{
+				MemoryConsumption: 15,
+				FunctionsCount:    5,
+				ClassesCount:      3,
+				Scripts:           []string{"/preload.php", "/src/Kernel.php"},
+			},
+		},
+		"web/web2": {
+			State:     observer.NodeStateDegraded,
+			CacheFull: true,
+			Memory:    observer.Memory{Total: 100, Used: 90, Free: 0, Wasted: 10},
+			KeyHits:   observer.KeyHits{Hits: 50, Misses: 50},
+			Keys:      observer.Keys{TotalPrime: 200, UsedKeys: 150, UsedScripts: 120},
+			Restarts:  observer.Restarts{HashCount: 1},
+			Preload: &observer.NodePreloadStatus{
+				MemoryConsumption: 30,
+				FunctionsCount:    7,
+				ClassesCount:      2,
+				Scripts:           []string{"/preload.php", "/src/App.php"},
+			},
+		},
+		// counted in states, but not summarized
+		"web/web3": {
+			State:   observer.NodeStateDisabled,
+			Memory:  observer.Memory{Total: 100, Used: 100},
+			KeyHits: observer.KeyHits{Hits: 1000},
+		},
+		// statistics not pulled yet
+		"web/web4": {},
+	})
+
+	summary, err := SummarizeGroup(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.NodesCount != 4 || summary.ReportingNodesCount != 3 {
+		t.Errorf("got %d nodes, %d reporting", summary.NodesCount, summary.ReportingNodesCount)
+	}
+
+	expectedStates := map[observer.NodeState]int{
+		observer.NodeStateEnabled:  1,
+		observer.NodeStateDegraded: 1,
+		observer.NodeStateDisabled: 1,
+		observer.NodeStateEmpty:    0,
+	}
+	if !reflect.DeepEqual(summary.NodeStates, expectedStates) {
+		t.Errorf("node states: got %v", summary.NodeStates)
+	}
+
+	if summary.Memory != (MemorySummary{Total: 200, Used: 150, Free: 30, Wasted: 20}) {
+		t.Errorf("memory: got %+v", summary.Memory)
+	}
+
+	if summary.KeyHits != (KeyHitsSummary{Hits: 140, Misses: 60, HitRatio: 0.7}) {
+		t.Errorf("key hits: got %+v", summary.KeyHits)
+	}
+
+	// nodes without rates do not contribute
+	if summary.Rates != (RatesSummary{HitsPerSecond: 30, MissesPerSecond: 10, HitRatio: 0.75, NewOutOfMemoryRestarts: 1}) {
+		t.Errorf("rates: got %+v", summary.Rates)
+	}
+
+	if summary.Keys != (KeysSummary{Total: 400, Used: 200, Utilisation: 0.5}) {
+		t.Errorf("keys: got %+v", summary.Keys)
+	}
+
+	if summary.Restarts != (RestartsSummary{OutOfMemoryCount: 1, HashCount: 1, ManualCount: 2}) {
+		t.Errorf("restarts: got %+v", summary.Restarts)
+	}
+
+	expectedPreload := PreloadSummary{
+		NodesCount:        2,
+		MemoryConsumption: 45,
+		MemoryRatio:       0.3,
+		ScriptsCount:      3,
+		FunctionsCount:    7,
+		ClassesCount:      3,
+	}
+	if summary.Preload != expectedPreload {
+		t.Errorf("preload: got %+v", summary.Preload)
+	}
+
+	if !reflect.DeepEqual(summary.CacheFullNodes, []NodeRef{{"web", "web2"}}) {
+		t.Errorf("cache full nodes: got %v", summary.CacheFullNodes)
+	}
+
+	expectedMemoryUsed := Distribution{Min: 60, Max: 90, Mean: 75, P50: 60, P90: 90, P99: 90}
+	if summary.Distributions[DistributionMemoryUsed] != expectedMemoryUsed {
+		t.Errorf("used memory distribution: got %+v", summary.Distributions[DistributionMemoryUsed])
+	}
+
+	if summary.Groups != nil {
+		t.Errorf("group summary contains groups: %v", summary.Groups)
+	}
+}
+
+func TestSummarizeGroupWithoutReportingNodes(t *testing.T) {
+	statuses := buildNodeStatuses(map[string]observer.NodeOpcacheStatus{
+		"web/web1": {},
+		"web/web2": {State: observer.NodeStateDisabled},
+	})
+
+	summary, err := SummarizeGroup(statuses, "production", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.ReportingNodesCount != 1 || summary.KeyHits.HitRatio != 0 || summary.Keys.Utilisation != 0 || summary.Rates.HitRatio != 0 {
+		t.Errorf("got %+v", summary)
+	}
+
+	if len(summary.Distributions) != 0 || len(summary.CacheFullNodes) != 0 {
+		t.Errorf("got distributions %v, cache full nodes %v", summary.Distributions, summary.CacheFullNodes)
+	}
+}
+
+func TestSummarizeCluster(t *testing.T) {
+	statuses := buildNodeStatuses(map[string]observer.NodeOpcacheStatus{
+		"web/web1": {State: observer.NodeStateEnabled, Memory: observer.Memory{Total: 100, Used: 40}},
+		"web/web2": {State: observer.NodeStateEnabled, Memory: observer.Memory{Total: 100, Used: 60}},
+		"api/api1": {State: observer.NodeStateEnabled, Memory: observer.Memory{Total: 50, Used: 10}, CacheFull: true},
+	})
+
+	summary, err := SummarizeCluster(statuses, "production")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.GroupName != "" || summary.NodesCount != 3 || summary.Memory.Total != 250 || summary.Memory.Used != 110 {
+		t.Errorf("got cluster summary %+v", summary)
+	}
+
+	if !reflect.DeepEqual(summary.CacheFullNodes, []NodeRef{{"api", "api1"}}) {
+		t.Errorf("cache full nodes: got %v", summary.CacheFullNodes)
+	}
+
+	if len(summary.Groups) != 2 {
+		t.Fatalf("expected summaries of 2 groups, got %d", len(summary.Groups))
+	}
+
+	if web := summary.Groups["web"]; web.GroupName != "web" || web.NodesCount != 2 || web.Memory.Used != 100 {
+		t.Errorf("web summary: got %+v", web)
+	}
+
+	if api := summary.Groups["api"]; api.NodesCount != 1 || api.Memory.Total != 50 {
+		t.Errorf("api summary: got %+v", api)
+	}
+}
+
+func TestSummarizeScopeNotFound(t *testing.T) {
+	statuses := buildNodeStatuses(map[string]observer.NodeOpcacheStatus{"web/web1": {}})
+
+	if _, err := SummarizeCluster(statuses, "staging"); err != ErrScopeNotFound {
+		t.Errorf("unknown cluster: got error %v", err)
+	}
+
+	if _, err := SummarizeGroup(statuses, "production", "api"); err != ErrScopeNotFound {
+		t.Errorf("unknown group: got error %v", err)
+	}
+
+	if _, err := SummarizeGroup(statuses, "production", ""); err != ErrScopeNotFound {
+		t.Errorf("empty group: got error %v", err)
+	}
+}
+
+func TestBuildDistribution(t *testing.T) {
+	values := []float64{}
+	for value := 100; value >= 1; value-- {
+		values = append(values, float64(value))
+	}
+
+	expected := Distribution{Min: 1, Max: 100, Mean: 50.5, P50: 50, P90: 90, P99: 99}
+	if distribution := buildDistribution(values); distribution != expected {
+		t.Errorf("got %+v, want %+v", distribution, expected)
+	}
+
+	if distribution := buildDistribution([]float64{}); distribution != (Distribution{}) {
+		t.Errorf("empty values: got %+v", distribution)
+	}
+}
@@ -7,21 +7,98 @@ const DefaultStatsdPort = 8125
 
 const DefaultRefreshIntervalSeconds = 3600
 
+const DefaultRateLimitRequestsPerMinute = 30
+const DefaultRateLimitBurst = 5
+
+const DefaultRetryAttempts = 2
+const DefaultRetryInitialDelaySeconds = 1
+const DefaultRetryMaxDelaySeconds = 10
+
+const DefaultCircuitBreakerFailureThreshold = 5
+const DefaultCircuitBreakerOpenIntervalSeconds = 0 // derived from pull interval of group
+const DefaultCircuitBreakerMaxOpenIntervalSeconds = 3600
+
+// Transports of requests to agent
+const (
+	AgentTransportHTTP    = "http"
+	AgentTransportFastCGI = "fastcgi"
+)
+
 // ApplicationConfig represents application configuration
 type ApplicationConfig struct {
 	PullIntervalSeconds int64
-	Clusters            map[string]ClusterConfig
-	UI                  UIConfig
-	Metrics             MetricsConfig
+	// Maximum size of agent response, zero means default limit
+	MaxResponseSizeBytes int64
+	Clusters             map[string]ClusterConfig
+	Retry                RetryConfig
+	CircuitBreaker       CircuitBreakerConfig
+	UI                   UIConfig
+	Metrics              MetricsConfig
+	Alerts               AlertsConfig
+	Advisor              AdvisorConfig
+}
+
+// RetryConfig defines retries of failed agent pull within one pull cycle
+type RetryConfig struct {
+	// Count of retries after failed pull, zero disables retries
+	Attempts int
+	// Delay before first retry, doubled on every next retry up to max delay
+	InitialDelaySeconds int64
+	MaxDelaySeconds     int64
+}
+
+// CircuitBreakerConfig defines backing off of polling of repeatedly failing nodes
+type CircuitBreakerConfig struct {
+	// Count of consecutive failed pulls opening breaker, zero disables breaker
+	FailureThreshold int
+	// Time of skipping pulls of node after breaker opened, doubled every time trial pull fails.
+	// Zero means two pull intervals of group, so at least one scheduled pull skipped.
+	OpenIntervalSeconds int64
+	// Limit of doubled open interval, never below initial open interval
+	MaxOpenIntervalSeconds int64
+}
+
+// AdvisorConfig configures best-practice checks of node settings
+type AdvisorConfig struct {
+	DisabledRules map[string]bool
+}
+
+// AlertsConfig enables checks raising alerts
+type AlertsConfig struct {
+	ConfigurationDrift bool
+	JitBufferExhausted bool
 }
 
 type ClusterConfig struct {
-	Groups map[string]GroupConfig
+	// Interval of pulling nodes of cluster, global pull interval used when zero
+	PullIntervalSeconds int64
+	// Production clusters are additionally checked by advisor rules relevant only to production
+	Production bool
+	Groups     map[string]GroupConfig
 }
 
 type UIConfig struct {
 	Host string
 	Port int
+	// Token accepted in "Authorization: Bearer" header of mutating API requests
+	// instead of CSRF token. Empty value disables bearer authentication.
+	APIToken  string
+	RateLimit RateLimitConfig
+	TLS       *TLSConfig // nil when dashboard served over plain HTTP
+}
+
+// TLSConfig defines certificates for serving dashboard over HTTPS
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // optional, client certificates signed by this CA required when defined
+	RedirectPort int    // optional, port of plain HTTP listener redirecting to HTTPS
+}
+
+// RateLimitConfig limits mutating API requests per client
+type RateLimitConfig struct {
+	RequestsPerMinute int
+	Burst             int
 }
 
 type MetricsConfig struct {
@@ -43,6 +120,25 @@ type GroupConfig struct {
 	UrlPattern           string
 	Hosts                []string
 	BasicAuthCredentials *BasicAuthCredentials
+	// Interval of pulling nodes of group, interval of cluster used when zero
+	PullIntervalSeconds int64
+	// Interval of pulling script lists. When defined, regular pulls fetch only status counters.
+	// When zero, script lists fetched on every pull.
+	ScriptsPullIntervalSeconds int64
+	// Optional URL pattern of PHP-FPM status page, pulled together with agent
+	FpmStatusUrlPattern string
+	// Transport of requests to agent, AgentTransportHTTP when not defined
+	Transport string
+	// Defined when agent executed directly by PHP-FPM over FastCGI
+	FastCGI *FastCGIConfig
+}
+
+// FastCGIConfig defines how agent script executed by PHP-FPM without HTTP front end
+type FastCGIConfig struct {
+	// Address pattern of PHP-FPM, "{host}:9000" for TCP or "unix:/run/php-fpm.sock" for unix socket
+	AddressPattern string
+	// Absolute path to agent script on node
+	ScriptPath string
 }
 
 type BasicAuthCredentials struct {
@@ -11,19 +11,57 @@ import (
 
 type yamlConfig struct {
 	PullIntervalSeconds *int64                       `yaml:"pullInterval"`
+	MaxResponseSize     int64                        `yaml:"maxResponseSize"`
 	Clusters            map[string]yamlClusterConfig `yaml:"clusters"`
+	Retry               *yamlRetryConfig             `yaml:"retry"`
+	CircuitBreaker      *yamlCircuitBreakerConfig    `yaml:"circuitBreaker"`
 	UI                  *yamlUIConfig                `yaml:"ui"`
 	Metrics             *yamlMetricsConfig           `yaml:"metrics"`
+	Alerts              *yamlAlertsConfig            `yaml:"alerts"`
+	Advisor             *yamlAdvisorConfig           `yaml:"advisor"`
+}
+
+type yamlRetryConfig struct {
+	Attempts     *int   `yaml:"attempts"`
+	InitialDelay *int64 `yaml:"initialDelay"`
+	MaxDelay     *int64 `yaml:"maxDelay"`
+}
+
+type yamlCircuitBreakerConfig struct {
+	FailureThreshold *int   `yaml:"failureThreshold"`
+	OpenInterval     *int64 `yaml:"openInterval"`
+	MaxOpenInterval  *int64 `yaml:"maxOpenInterval"`
+}
+
+type yamlAdvisorConfig struct {
+	Rules map[string]bool `yaml:"rules"`
+}
+
+type yamlAlertsConfig struct {
+	ConfigurationDrift bool `yaml:"configurationDrift"`
+	JitBufferExhausted bool `yaml:"jitBufferExhausted"`
 }
 
 type yamlClusterConfig struct {
-	Groups map[string]yamlGroupConfig `yaml:"groups"`
+	PullInterval int64                      `yaml:"pullInterval"`
+	Production   bool                       `yaml:"production"`
+	Groups       map[string]yamlGroupConfig `yaml:"groups"`
 }
 
 type yamlGroupConfig struct {
 	UrlPattern           string                    `yaml:"urlPattern"`
 	Hosts                []string                  `yaml:"hosts"`
 	BasicAuthCredentials *yamlBasicAuthCredentials `yaml:"basicAuth"`
+	PullInterval         int64                     `yaml:"pullInterval"`
+	ScriptsPullInterval  int64                     `yaml:"scriptsPullInterval"`
+	FpmStatusUrlPattern  string                    `yaml:"fpmStatusUrlPattern"`
+	Transport            string                    `yaml:"transport"`
+	FastCGI              *yamlFastCGIConfig        `yaml:"fastcgi"`
+}
+
+type yamlFastCGIConfig struct {
+	Address    string `yaml:"address"`
+	ScriptPath string `yaml:"scriptPath"`
 }
 
 type yamlBasicAuthCredentials struct {
@@ -32,8 +70,24 @@ type yamlBasicAuthCredentials struct {
 }
 
 type yamlUIConfig struct {
-	Host *string `yaml:"host"`
-	Port *int    `yaml:"port"`
+	Host      *string              `yaml:"host"`
+	Port      *int                 `yaml:"port"`
+	APIToken  *string              `yaml:"apiToken"`
+	RateLimit *yamlRateLimitConfig `yaml:"rateLimit"`
+	TLS       *yamlTLSConfig       `yaml:"tls"`
+}
+
+type yamlTLSConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	CertFile     string `yaml:"cert"`
+	KeyFile      string `yaml:"key"`
+	ClientCAFile string `yaml:"clientCa"`
+	RedirectPort int    `yaml:"redirectPort"`
+}
+
+type yamlRateLimitConfig struct {
+	RequestsPerMinute *int `yaml:"requestsPerMinute"`
+	Burst             *int `yaml:"burst"`
 }
 
 type yamlMetricsConfig struct {
@@ -90,9 +144,26 @@ func (reader *YAMLConfigReader) ReadConfig(path string) ApplicationConfig {
 		PullIntervalSeconds: DefaultRefreshIntervalSeconds,
 		Clusters:            map[string]ClusterConfig{},
 		Metrics:             MetricsConfig{},
+		Retry: RetryConfig{
+			Attempts:            DefaultRetryAttempts,
+			InitialDelaySeconds: DefaultRetryInitialDelaySeconds,
+			MaxDelaySeconds:     DefaultRetryMaxDelaySeconds,
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureThreshold:       DefaultCircuitBreakerFailureThreshold,
+			OpenIntervalSeconds:    DefaultCircuitBreakerOpenIntervalSeconds,
+			MaxOpenIntervalSeconds: DefaultCircuitBreakerMaxOpenIntervalSeconds,
+		},
+		Advisor: AdvisorConfig{
+			DisabledRules: map[string]bool{},
+		},
 		UI: UIConfig{
 			Host: DefaultHTTPHost,
 			Port: DefaultHTTPPort,
+			RateLimit: RateLimitConfig{
+				RequestsPerMinute: DefaultRateLimitRequestsPerMinute,
+				Burst:             DefaultRateLimitBurst,
+			},
 		},
 	}
 
@@ -101,17 +172,44 @@ func (reader *YAMLConfigReader) ReadConfig(path string) ApplicationConfig {
 		config.PullIntervalSeconds = *yamlConfig.PullIntervalSeconds
 	}
 
+	// Agent response limit
+	config.MaxResponseSizeBytes = yamlConfig.MaxResponseSize
+
 	// PHP Node Cluster
 	for clusterName, yamlClusterConfig := range yamlConfig.Clusters {
 		config.Clusters[clusterName] = ClusterConfig{
-			Groups: map[string]GroupConfig{},
+			PullIntervalSeconds: yamlClusterConfig.PullInterval,
+			Production:          yamlClusterConfig.Production,
+			Groups:              map[string]GroupConfig{},
 		}
 
 		for groupName, yamlGroupConfig := range yamlClusterConfig.Groups {
 			clusterGroupConfig := GroupConfig{
-				UrlPattern:           yamlGroupConfig.UrlPattern,
-				Hosts:                yamlGroupConfig.Hosts,
-				BasicAuthCredentials: nil,
+				UrlPattern:                 yamlGroupConfig.UrlPattern,
+				Hosts:                      yamlGroupConfig.Hosts,
+				BasicAuthCredentials:       nil,
+				PullIntervalSeconds:        yamlGroupConfig.PullInterval,
+				ScriptsPullIntervalSeconds: yamlGroupConfig.ScriptsPullInterval,
+				FpmStatusUrlPattern:        yamlGroupConfig.FpmStatusUrlPattern,
+				Transport:                  AgentTransportHTTP,
+			}
+
+			switch yamlGroupConfig.Transport {
+			case "", AgentTransportHTTP:
+			case AgentTransportFastCGI:
+				if yamlGroupConfig.FastCGI == nil ||
+					yamlGroupConfig.FastCGI.Address == "" ||
+					yamlGroupConfig.FastCGI.ScriptPath == "" {
+					log.Fatalf("FastCGI address and script path must be defined for group '%s'", groupName)
+				}
+
+				clusterGroupConfig.Transport = AgentTransportFastCGI
+				clusterGroupConfig.FastCGI = &FastCGIConfig{
+					AddressPattern: yamlGroupConfig.FastCGI.Address,
+					ScriptPath:     yamlGroupConfig.FastCGI.ScriptPath,
+				}
+			default:
+				log.Fatalf("Unknown transport '%s' of group '%s'", yamlGroupConfig.Transport, groupName)
 			}
 
 			if yamlGroupConfig.BasicAuthCredentials != nil {
@@ -125,6 +223,36 @@ func (reader *YAMLConfigReader) ReadConfig(path string) ApplicationConfig {
 		}
 	}
 
+	// Retries of failed pulls
+	if yamlConfig.Retry != nil {
+		if yamlConfig.Retry.Attempts != nil {
+			config.Retry.Attempts = *yamlConfig.Retry.Attempts
+		}
+
+		if yamlConfig.Retry.InitialDelay != nil {
+			config.Retry.InitialDelaySeconds = *yamlConfig.Retry.InitialDelay
+		}
+
+		if yamlConfig.Retry.MaxDelay != nil {
+			config.Retry.MaxDelaySeconds = *yamlConfig.Retry.MaxDelay
+		}
+	}
+
+	// Circuit breaker of failing nodes
+	if yamlConfig.CircuitBreaker != nil {
+		if yamlConfig.CircuitBreaker.FailureThreshold != nil {
+			config.CircuitBreaker.FailureThreshold = *yamlConfig.CircuitBreaker.FailureThreshold
+		}
+
+		if yamlConfig.CircuitBreaker.OpenInterval != nil {
+			config.CircuitBreaker.OpenIntervalSeconds = *yamlConfig.CircuitBreaker.OpenInterval
+		}
+
+		if yamlConfig.CircuitBreaker.MaxOpenInterval != nil {
+			config.CircuitBreaker.MaxOpenIntervalSeconds = *yamlConfig.CircuitBreaker.MaxOpenInterval
+		}
+	}
+
 	// UI
 	if yamlConfig.UI != nil {
 		if yamlConfig.UI.Host != nil {
@@ -134,6 +262,33 @@ func (reader *YAMLConfigReader) ReadConfig(path string) ApplicationConfig {
 		if yamlConfig.UI.Port != nil {
 			config.UI.Port = *yamlConfig.UI.Port
 		}
+
+		if yamlConfig.UI.APIToken != nil {
+			config.UI.APIToken = *yamlConfig.UI.APIToken
+		}
+
+		if yamlConfig.UI.RateLimit != nil {
+			if yamlConfig.UI.RateLimit.RequestsPerMinute != nil {
+				config.UI.RateLimit.RequestsPerMinute = *yamlConfig.UI.RateLimit.RequestsPerMinute
+			}
+
+			if yamlConfig.UI.RateLimit.Burst != nil {
+				config.UI.RateLimit.Burst = *yamlConfig.UI.RateLimit.Burst
+			}
+		}
+
+		if yamlConfig.UI.TLS != nil && yamlConfig.UI.TLS.Enabled {
+			if yamlConfig.UI.TLS.CertFile == "" || yamlConfig.UI.TLS.KeyFile == "" {
+				log.Fatalf("TLS certificate and key must be defined when TLS enabled")
+			}
+
+			config.UI.TLS = &TLSConfig{
+				CertFile:     yamlConfig.UI.TLS.CertFile,
+				KeyFile:      yamlConfig.UI.TLS.KeyFile,
+				ClientCAFile: yamlConfig.UI.TLS.ClientCAFile,
+				RedirectPort: yamlConfig.UI.TLS.RedirectPort,
+			}
+		}
 	}
 
 	// Metrics
@@ -166,5 +321,20 @@ func (reader *YAMLConfigReader) ReadConfig(path string) ApplicationConfig {
 		}
 	}
 
+	// Alerts
+	if yamlConfig.Alerts != nil {
+		config.Alerts.ConfigurationDrift = yamlConfig.Alerts.ConfigurationDrift
+		config.Alerts.JitBufferExhausted = yamlConfig.Alerts.JitBufferExhausted
+	}
+
+	// Advisor
+	if yamlConfig.Advisor != nil {
+		for ruleID, isEnabled := range yamlConfig.Advisor.Rules {
+			if !isEnabled {
+				config.Advisor.DisabledRules[ruleID] = true
+			}
+		}
+	}
+
 	return config
 }
@@ -12,12 +12,18 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/GoMetric/opcache-dashboard/advisor"
+	"github.com/GoMetric/opcache-dashboard/alerting"
+	"github.com/GoMetric/opcache-dashboard/analytics"
 	"github.com/GoMetric/opcache-dashboard/configuration"
 	"github.com/GoMetric/opcache-dashboard/metrics"
 	"github.com/GoMetric/opcache-dashboard/observer"
+	"github.com/GoMetric/opcache-dashboard/server"
 	"github.com/GoMetric/opcache-dashboard/ui"
 	"github.com/NYTimes/gziphandler"
 	"github.com/gorilla/mux"
@@ -122,9 +128,45 @@ func main() {
 
 	// Build observer
 	var o = observer.Observer{
-		Clusters: applicationConfig.Clusters,
+		Clusters:             applicationConfig.Clusters,
+		MaxResponseSizeBytes: applicationConfig.MaxResponseSizeBytes,
+		Retry:                applicationConfig.Retry,
+		CircuitBreaker:       applicationConfig.CircuitBreaker,
 	}
 
+	// Alerts raised by checks after every pull
+	alertRegistry := alerting.NewRegistry()
+
+	if applicationConfig.Alerts.ConfigurationDrift {
+		o.AddPullCompleteListener(func() {
+			alerting.CheckConfigurationDrift(alertRegistry, o.GetOpcacheStatistics())
+		})
+	}
+
+	if applicationConfig.Alerts.JitBufferExhausted {
+		o.AddPullCompleteListener(func() {
+			alerting.CheckJitBufferExhausted(alertRegistry, o.GetOpcacheStatistics())
+		})
+	}
+
+	// Live events streamed to dashboards
+	eventBroker := server.NewEventBroker()
+
+	o.AddEventListener(func(event observer.Event) {
+		eventBroker.Publish(event.Type, event)
+	})
+
+	alertRegistry.AddListener(func(alert alerting.Alert, resolved bool) {
+		if resolved {
+			eventBroker.Publish("alertResolved", alert)
+		} else {
+			eventBroker.Publish("alertRaised", alert)
+		}
+	})
+
+	// Senders of rolled up statistics of groups and clusters
+	var summarySenders []analytics.SummarySenderInterface
+
 	// Add StatsD sender if configured
 	if applicationConfig.Metrics.Statsd != nil {
 		var statsdClient = GoMetricStatsdClient.NewClient(
@@ -141,15 +183,21 @@ func main() {
 		}
 
 		o.AddMetricSender(statsdMetricSender)
+		o.AddNodeHealthSender(statsdMetricSender)
+		summarySenders = append(summarySenders, statsdMetricSender)
 	}
 
 	// // Add prometheus sender if configured
 	if applicationConfig.Metrics.Prometheus != nil {
 		prometheusRegistry := prometheus.NewRegistry()
-		o.AddMetricSender(metrics.NewPrometheusMetricSender(
+		prometheusMetricSender := metrics.NewPrometheusMetricSender(
 			prometheusRegistry,
 			applicationConfig.Metrics.Prometheus.Prefix,
-		))
+		)
+
+		o.AddMetricSender(prometheusMetricSender)
+		o.AddNodeHealthSender(prometheusMetricSender)
+		summarySenders = append(summarySenders, prometheusMetricSender)
 
 		router.Handle(
 			"/api/nodes/statistics/prometheus",
@@ -157,21 +205,33 @@ func main() {
 		)
 	}
 
+	if len(summarySenders) > 0 {
+		o.AddPullCompleteListener(func() {
+			analytics.SendSummaries(o.GetOpcacheStatistics(), summarySenders)
+		})
+	}
+
 	// opcache statistics common request handler
 	router.Handle(
 		"/api/nodes/statistics/opcache",
 		gziphandler.GzipHandler(
 			http.HandlerFunc(
 				func(w http.ResponseWriter, r *http.Request) {
-					var jsonBody []byte
-
-					if r.URL.Query().Get("pretty") == "1" {
-						jsonBody, _ = json.MarshalIndent(o.GetOpcacheStatistics(), "", "    ")
-					} else {
-						jsonBody, _ = json.Marshal(o.GetOpcacheStatistics())
+					opcacheStatistics := o.GetOpcacheStatistics()
+
+					// script lists may be huge, so they may be skipped and fetched from scripts API
+					if r.URL.Query().Get("scripts") == "0" {
+						for _, groups := range opcacheStatistics {
+							for _, hosts := range groups {
+								for hostName, status := range hosts {
+									status.Scripts = nil
+									hosts[hostName] = status
+								}
+							}
+						}
 					}
 
-					w.Write(jsonBody)
+					server.WriteJSON(w, r, opcacheStatistics)
 				},
 			),
 		),
@@ -183,45 +243,357 @@ func main() {
 		gziphandler.GzipHandler(
 			http.HandlerFunc(
 				func(w http.ResponseWriter, r *http.Request) {
-					var jsonBody []byte
+					server.WriteJSON(w, r, o.GetApcuStatistics())
+				},
+			),
+		),
+	)
 
-					if r.URL.Query().Get("pretty") == "1" {
-						jsonBody, _ = json.MarshalIndent(o.GetApcuStatistics(), "", "    ")
-					} else {
-						jsonBody, _ = json.Marshal(o.GetApcuStatistics())
+	// PHP-FPM pool statuses of groups with configured status page
+	router.HandleFunc(
+		"/api/nodes/statistics/fpm",
+		func(w http.ResponseWriter, r *http.Request) {
+			server.WriteJSON(w, r, o.GetFpmStatistics())
+		},
+	)
+
+	// scripts of node, group or cluster filtered, sorted and paginated
+	scriptsHandler := gziphandler.GzipHandler(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				vars := mux.Vars(r)
+				queryParams := r.URL.Query()
+
+				scriptQuery := analytics.ScriptQuery{
+					ClusterName: vars["clusterName"],
+					GroupName:   vars["groupName"],
+					HostName:    vars["hostName"],
+					PathPrefix:  queryParams.Get("prefix"),
+					SortBy:      queryParams.Get("sort"),
+					Ascending:   queryParams.Get("order") == "asc",
+					Aggregate:   queryParams.Get("aggregate") == "1",
+					Cursor:      queryParams.Get("cursor"),
+				}
+
+				if limit, err := strconv.Atoi(queryParams.Get("limit")); err == nil {
+					scriptQuery.Limit = limit
+				}
+
+				if preloaded := queryParams.Get("preloaded"); preloaded != "" {
+					isPreloaded := preloaded == "1"
+					scriptQuery.Preloaded = &isPreloaded
+				}
+
+				if pathRegex := queryParams.Get("regex"); pathRegex != "" {
+					compiledPathRegex, err := regexp.Compile(pathRegex)
+					if err != nil {
+						server.WriteJSONError(w, http.StatusBadRequest, err)
+						return
 					}
 
-					w.Write(jsonBody)
+					scriptQuery.PathRegex = compiledPathRegex
+				}
+
+				scriptPage, err := analytics.QueryScripts(o.GetOpcacheStatistics(), scriptQuery, time.Now())
+
+				if err == analytics.ErrScopeNotFound {
+					server.WriteJSONError(w, http.StatusNotFound, err)
+					return
+				} else if err != nil {
+					server.WriteJSONError(w, http.StatusBadRequest, err)
+					return
+				}
+
+				server.WriteJSON(w, r, scriptPage)
+			},
+		),
+	)
+
+	router.Handle("/api/clusters/{clusterName}/scripts", scriptsHandler)
+	router.Handle("/api/clusters/{clusterName}/groups/{groupName}/scripts", scriptsHandler)
+	router.Handle("/api/clusters/{clusterName}/groups/{groupName}/nodes/{hostName}/scripts", scriptsHandler)
+
+	// comparison of scripts cached on different nodes of group
+	router.Handle(
+		"/api/clusters/{clusterName}/groups/{groupName}/coverage",
+		gziphandler.GzipHandler(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					vars := mux.Vars(r)
+
+					coverage, err := analytics.CompareGroupScripts(
+						o.GetOpcacheStatistics(),
+						vars["clusterName"],
+						vars["groupName"],
+					)
+
+					if err != nil {
+						server.WriteJSONError(w, http.StatusNotFound, err)
+						return
+					}
+
+					server.WriteJSON(w, r, coverage)
 				},
 			),
 		),
 	)
 
-	// re-read opcache stat from agents
+	// configuration drift between nodes of cluster or group
+	configurationDriftHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+
+			drift, err := analytics.DetectConfigurationDrift(
+				o.GetOpcacheStatistics(),
+				vars["clusterName"],
+				vars["groupName"],
+			)
+
+			if err != nil {
+				server.WriteJSONError(w, http.StatusNotFound, err)
+				return
+			}
+
+			server.WriteJSON(w, r, drift)
+		},
+	)
+
+	router.Handle("/api/clusters/{clusterName}/configuration/drift", configurationDriftHandler)
+	router.Handle("/api/clusters/{clusterName}/groups/{groupName}/configuration/drift", configurationDriftHandler)
+
+	// rolled up statistics of cluster and its groups
+	router.HandleFunc(
+		"/api/clusters/{clusterName}/summary",
+		func(w http.ResponseWriter, r *http.Request) {
+			summary, err := analytics.SummarizeCluster(o.GetOpcacheStatistics(), mux.Vars(r)["clusterName"])
+			if err != nil {
+				server.WriteJSONError(w, http.StatusNotFound, err)
+				return
+			}
+
+			server.WriteJSON(w, r, summary)
+		},
+	)
+
+	// rolled up statistics of group
+	router.HandleFunc(
+		"/api/clusters/{clusterName}/groups/{groupName}/summary",
+		func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+
+			summary, err := analytics.SummarizeGroup(o.GetOpcacheStatistics(), vars["clusterName"], vars["groupName"])
+			if err != nil {
+				server.WriteJSONError(w, http.StatusNotFound, err)
+				return
+			}
+
+			server.WriteJSON(w, r, summary)
+		},
+	)
+
+	// sizing recommendations of opcache directives
 	router.HandleFunc(
+		"/api/recommendations",
+		func(w http.ResponseWriter, r *http.Request) {
+			server.WriteJSON(w, r, analytics.RecommendSizing(o.GetOpcacheStatistics()))
+		},
+	)
+
+	// best-practice checks of opcache and APCu settings
+	productionClusters := map[string]bool{}
+	for clusterName, clusterConfig := range applicationConfig.Clusters {
+		productionClusters[clusterName] = clusterConfig.Production
+	}
+
+	settingsAdvisor := advisor.NewAdvisor(applicationConfig.Advisor.DisabledRules, productionClusters)
+
+	router.HandleFunc(
+		"/api/advisor",
+		func(w http.ResponseWriter, r *http.Request) {
+			server.WriteJSON(w, r, settingsAdvisor.Advise(o.GetOpcacheStatistics(), o.GetApcuStatistics()))
+		},
+	)
+
+	// result of last pull of every node
+	router.HandleFunc(
+		"/api/nodes/health",
+		func(w http.ResponseWriter, r *http.Request) {
+			server.WriteJSON(w, r, o.GetNodesHealth())
+		},
+	)
+
+	// protocol versions and capabilities of node agents, outdated agents flagged
+	router.HandleFunc(
+		"/api/agents",
+		func(w http.ResponseWriter, r *http.Request) {
+			server.WriteJSON(w, r, o.GetAgentsInfo())
+		},
+	)
+
+	// page of APCu user cache entries of node
+	router.HandleFunc(
+		"/api/clusters/{clusterName}/groups/{groupName}/nodes/{hostName}/apcu/keys",
+		func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+			queryParams := r.URL.Query()
+
+			offset, _ := strconv.Atoi(queryParams.Get("offset"))
+			limit, _ := strconv.Atoi(queryParams.Get("limit"))
+
+			keysPage, err := o.ListApcuKeys(
+				vars["clusterName"],
+				vars["groupName"],
+				vars["hostName"],
+				queryParams.Get("prefix"),
+				offset,
+				limit,
+			)
+
+			if err == observer.ErrNodeNotFound {
+				server.WriteJSONError(w, http.StatusNotFound, err)
+				return
+			} else if err != nil {
+				server.WriteJSONError(w, http.StatusBadGateway, err)
+				return
+			}
+
+			server.WriteJSON(w, r, keysPage)
+		},
+	).Methods(http.MethodGet)
+
+	// Server-Sent Events stream of node updates, health changes, reset outcomes and alerts
+	router.Handle("/api/events", eventBroker)
+
+	// active alerts
+	router.HandleFunc(
+		"/api/alerts",
+		func(w http.ResponseWriter, r *http.Request) {
+			server.WriteJSON(w, r, alertRegistry.Active())
+		},
+	)
+
+	// mutating requests must be POST with CSRF or bearer token and are rate limited
+	csrfProtection := server.NewCsrfProtection(applicationConfig.UI.APIToken)
+	rateLimiter := server.NewRateLimiter(
+		applicationConfig.UI.RateLimit.RequestsPerMinute,
+		applicationConfig.UI.RateLimit.Burst,
+	)
+
+	mutatingRouter := router.Methods(http.MethodPost).Subrouter()
+	mutatingRouter.Use(rateLimiter.Middleware, csrfProtection.Middleware)
+
+	// re-read opcache stat from agents
+	mutatingRouter.HandleFunc(
 		"/api/nodes/statistics/refresh",
 		func(w http.ResponseWriter, r *http.Request) {
-			go o.PullAgents()
+			// concurrent refresh requests share single pull in progress
+			o.RequestPull()
 			w.Write([]byte("OK"))
 		},
 	)
 
 	// reset opcache on php node
-	router.HandleFunc(
+	mutatingRouter.HandleFunc(
 		"/api/nodes/{clusterName}/{groupName}/{hostName}/resetOpcache",
 		func(w http.ResponseWriter, r *http.Request) {
 			vars := mux.Vars(r)
 
-			go o.ResetOpcache(
+			go func() {
+				err := o.ResetOpcache(
+					vars["clusterName"],
+					vars["groupName"],
+					vars["hostName"],
+				)
+
+				if err != nil {
+					log.Println(fmt.Sprintf("%v", err))
+				}
+			}()
+
+			w.Write([]byte("OK"))
+		},
+	)
+
+	// invalidate single script in opcache of php node
+	mutatingRouter.HandleFunc(
+		"/api/nodes/{clusterName}/{groupName}/{hostName}/invalidateScript",
+		func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+
+			err := o.InvalidateScript(
 				vars["clusterName"],
 				vars["groupName"],
 				vars["hostName"],
+				r.FormValue("script"),
 			)
 
+			if err == observer.ErrNodeNotFound {
+				server.WriteJSONError(w, http.StatusNotFound, err)
+				return
+			} else if err != nil {
+				server.WriteJSONError(w, http.StatusBadRequest, err)
+				return
+			}
+
 			w.Write([]byte("OK"))
 		},
 	)
 
+	// delete APCu user cache entries by key or prefix on node or on all nodes of group
+	apcuDeleteHandler := func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		results, err := o.DeleteApcuKeys(
+			vars["clusterName"],
+			vars["groupName"],
+			vars["hostName"],
+			r.FormValue("key"),
+			r.FormValue("prefix"),
+		)
+
+		if err == observer.ErrNodeNotFound {
+			server.WriteJSONError(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			server.WriteJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		server.WriteJSON(w, r, results)
+	}
+
+	mutatingRouter.HandleFunc("/api/clusters/{clusterName}/groups/{groupName}/apcu/delete", apcuDeleteHandler)
+	mutatingRouter.HandleFunc("/api/clusters/{clusterName}/groups/{groupName}/nodes/{hostName}/apcu/delete", apcuDeleteHandler)
+
+	// clear whole APCu user cache on node or on all nodes of group
+	apcuClearHandler := func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		results, err := o.ClearApcuCache(
+			vars["clusterName"],
+			vars["groupName"],
+			vars["hostName"],
+		)
+
+		if err == observer.ErrNodeNotFound {
+			server.WriteJSONError(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			server.WriteJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		server.WriteJSON(w, r, results)
+	}
+
+	mutatingRouter.HandleFunc("/api/clusters/{clusterName}/groups/{groupName}/apcu/clear", apcuClearHandler)
+	mutatingRouter.HandleFunc("/api/clusters/{clusterName}/groups/{groupName}/nodes/{hostName}/apcu/clear", apcuClearHandler)
+
+	// mutating routes requested by other methods must not fall through to frontend routes
+	if err := server.RejectOtherMethods(router, mutatingRouter, http.MethodPost); err != nil {
+		log.Fatal(err)
+	}
+
 	// api status
 	router.HandleFunc(
 		"/api/status",
@@ -230,7 +602,7 @@ func main() {
 				"version":          Version,
 				"buildDate":        BuildDate,
 				"buildNumber":      BuildNumber,
-				"lastStatusUpdate": o.LastStatusUpdate,
+				"lastStatusUpdate": o.GetLastStatusUpdate(),
 			}
 
 			heartbeatJson, _ := json.Marshal(heartbeat)
@@ -247,6 +619,8 @@ func main() {
 	// frontend routes handler
 	router.PathPrefix("/").HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
+			csrfProtection.IssueToken(w, r)
+
 			var indexBody, _ = ui.Asset("index.html")
 			w.Write(indexBody)
 		},
@@ -266,12 +640,63 @@ func main() {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	log.Printf("Starting HTTP server at %s", httpAddress)
+	var httpsRedirectServer *http.Server
 
-	// start listening server
-	go func() {
-		log.Fatal(httpServer.ListenAndServe())
-	}()
+	if applicationConfig.UI.TLS != nil {
+		// serve over HTTPS with certificates reloaded on change
+		certificateReloader, err := server.NewCertificateReloader(
+			applicationConfig.UI.TLS.CertFile,
+			applicationConfig.UI.TLS.KeyFile,
+			applicationConfig.UI.TLS.ClientCAFile,
+		)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		certificateReloader.StartWatching(server.CertificateReloadCheckInterval)
+
+		httpServer.TLSConfig = certificateReloader.TLSConfig()
+
+		log.Printf("Starting HTTPS server at %s", httpAddress)
+
+		go func() {
+			err := httpServer.ListenAndServeTLS("", "")
+			if err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+
+		// redirect plain HTTP requests to HTTPS
+		if applicationConfig.UI.TLS.RedirectPort != 0 {
+			httpsRedirectServer = &http.Server{
+				Addr:           fmt.Sprintf("%s:%d", applicationConfig.UI.Host, applicationConfig.UI.TLS.RedirectPort),
+				Handler:        server.NewHTTPSRedirectHandler(applicationConfig.UI.Port),
+				ReadTimeout:    10 * time.Second,
+				WriteTimeout:   10 * time.Second,
+				MaxHeaderBytes: 1 << 20,
+			}
+
+			log.Printf("Starting HTTP to HTTPS redirect server at %s", httpsRedirectServer.Addr)
+
+			go func() {
+				err := httpsRedirectServer.ListenAndServe()
+				if err != http.ErrServerClosed {
+					log.Fatal(err)
+				}
+			}()
+		}
+	} else {
+		log.Printf("Starting HTTP server at %s", httpAddress)
+
+		// start listening server
+		go func() {
+			err := httpServer.ListenAndServe()
+			if err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
 
 	// gracefull shutdown
 	gracefullStopSignalHandler := make(chan os.Signal, 1)
@@ -286,8 +711,13 @@ func main() {
 		cancel()
 	}()
 
+	httpServer.RegisterOnShutdown(eventBroker.Close)
 	httpServer.Shutdown(ctx)
 
+	if httpsRedirectServer != nil {
+		httpsRedirectServer.Shutdown(ctx)
+	}
+
 	log.Printf("Server stopped successfully")
 
 	os.Exit(0)
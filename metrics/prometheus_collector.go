@@ -4,13 +4,27 @@ import (
 	"log"
 	"strings"
 
+	"github.com/GoMetric/opcache-dashboard/analytics"
 	"github.com/GoMetric/opcache-dashboard/observer"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Gauges of PHP-FPM pool status, removed when status page of node not available
+var fpmGaugeNames = []string{
+	"fpm_accepted_connections",
+	"fpm_listen_queue",
+	"fpm_max_listen_queue",
+	"fpm_idle_processes",
+	"fpm_active_processes",
+	"fpm_total_processes",
+	"fpm_max_children_reached",
+	"fpm_slow_requests",
+}
+
 type PrometheusMetricSender struct {
-	gauges map[string]prometheus.GaugeVec
-	Prefix string
+	gauges        map[string]prometheus.GaugeVec
+	summaryGauges map[string]prometheus.GaugeVec
+	Prefix        string
 }
 
 func NewPrometheusMetricSender(
@@ -18,7 +32,8 @@ func NewPrometheusMetricSender(
 	prefix string,
 ) *PrometheusMetricSender {
 	sender := PrometheusMetricSender{
-		gauges: map[string]prometheus.GaugeVec{},
+		gauges:        map[string]prometheus.GaugeVec{},
+		summaryGauges: map[string]prometheus.GaugeVec{},
 	}
 
 	sender.Prefix = prefix
@@ -32,9 +47,42 @@ func NewPrometheusMetricSender(
 		"opcache_keys_usedKeys",
 		"opcache_keys_usedScripts",
 		"opcache_keyHits_misses",
+		"opcache_rates_hits_per_second",
+		"opcache_rates_misses_per_second",
+		"opcache_rates_hit_ratio",
+		"opcache_rates_new_oom_restarts",
+		"opcache_rates_new_hash_restarts",
+		"opcache_state_enabled",
+		"opcache_state_disabled",
+		"opcache_state_empty",
+		"opcache_state_degraded",
+		"opcache_preload_memory_bytes",
+		"opcache_preload_scripts",
+		"opcache_preload_functions",
+		"opcache_preload_classes",
+		"opcache_jit_buffer_size_bytes",
+		"opcache_jit_buffer_free_bytes",
+		"opcache_jit_buffer_used_bytes",
 		"apcu_memory_free_bytes",
+		"apcu_memory_free_blocks",
+		"apcu_memory_largest_free_block_bytes",
+		"apcu_memory_fragmentation_percent",
+		"apcu_cache_entries",
+		"apcu_cache_hits",
+		"apcu_cache_misses",
+		"apcu_cache_inserts",
+		"apcu_cache_expunges",
+		"apcu_cache_memory_used_bytes",
+		"apcu_cache_hit_ratio",
+		"node_healthy",
+		"node_consecutive_failures",
+		"node_circuit_breaker_state_closed",
+		"node_circuit_breaker_state_open",
+		"node_circuit_breaker_state_halfOpen",
 	}
 
+	gaugeNames = append(gaugeNames, fpmGaugeNames...)
+
 	for _, gaugeName := range gaugeNames {
 		fullGaugeName := sender.buildFullMetricName(gaugeName)
 
@@ -49,6 +97,46 @@ func NewPrometheusMetricSender(
 		registry.MustRegister(sender.gauges[fullGaugeName])
 	}
 
+	// rolled up statistics of groups and clusters, cluster summary has empty group name
+	summaryGaugeNames := []string{
+		"opcache_summary_nodes_count",
+		"opcache_summary_reporting_nodes_count",
+		"opcache_summary_memory_total_bytes",
+		"opcache_summary_memory_used_bytes",
+		"opcache_summary_memory_free_bytes",
+		"opcache_summary_memory_wasted_bytes",
+		"opcache_summary_keyHits_ratio",
+		"opcache_summary_rates_hits_per_second",
+		"opcache_summary_rates_misses_per_second",
+		"opcache_summary_rates_hit_ratio",
+		"opcache_summary_keys_utilisation",
+		"opcache_summary_cache_full_nodes",
+		"opcache_summary_state_enabled_nodes",
+		"opcache_summary_state_disabled_nodes",
+		"opcache_summary_state_empty_nodes",
+		"opcache_summary_state_degraded_nodes",
+		"opcache_summary_restarts_oom",
+		"opcache_summary_restarts_hash",
+		"opcache_summary_restarts_manual",
+		"opcache_summary_preload_nodes_count",
+		"opcache_summary_preload_memory_bytes",
+		"opcache_summary_preload_scripts",
+	}
+
+	for _, gaugeName := range summaryGaugeNames {
+		fullGaugeName := sender.buildFullMetricName(gaugeName)
+
+		sender.summaryGauges[fullGaugeName] = *prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: fullGaugeName,
+				Help: gaugeName,
+			},
+			[]string{"clusterName", "groupName"},
+		)
+
+		registry.MustRegister(sender.summaryGauges[fullGaugeName])
+	}
+
 	return &sender
 }
 
@@ -61,26 +149,120 @@ func (s *PrometheusMetricSender) Send(
 	nodeOpcacheStatus := nodeStatistics.OpcacheStatistics
 	nodeApcuStatus := nodeStatistics.ApcuStatistics
 
-	clusterName = strings.ReplaceAll(clusterName, ".", "-")
-	groupName = strings.ReplaceAll(groupName, ".", "-")
-	hostName = strings.ReplaceAll(hostName, ".", "-")
+	gaugeNameValueMap := map[string]float64{
+		"opcache_scripts_count":       float64(len(nodeOpcacheStatus.Scripts)),
+		"opcache_memory_free_bytes":   float64(nodeOpcacheStatus.Memory.Free),
+		"opcache_memory_used_bytes":   float64(nodeOpcacheStatus.Memory.Used),
+		"opcache_memory_wasted_bytes": float64(nodeOpcacheStatus.Memory.Wasted),
+		"opcache_keys_free":           float64(nodeOpcacheStatus.Keys.Free),
+		"opcache_keys_usedKeys":       float64(nodeOpcacheStatus.Keys.UsedKeys),
+		"opcache_keys_usedScripts":    float64(nodeOpcacheStatus.Keys.UsedScripts),
+		"opcache_keyHits_misses":      float64(nodeOpcacheStatus.KeyHits.Misses),
+	}
+
+	// current state of node is 1, other states are 0
+	for _, nodeState := range observer.NodeStates {
+		gaugeNameValueMap["opcache_state_"+string(nodeState)] = 0
+	}
+	gaugeNameValueMap["opcache_state_"+string(nodeOpcacheStatus.State)] = 1
 
-	gaugeNameValueMap := map[string]int{
-		"opcache_scripts_count":       len(nodeOpcacheStatus.Scripts),
-		"opcache_memory_free_bytes":   nodeOpcacheStatus.Memory.Free,
-		"opcache_memory_used_bytes":   nodeOpcacheStatus.Memory.Used,
-		"opcache_memory_wasted_bytes": nodeOpcacheStatus.Memory.Wasted,
-		"opcache_keys_free":           nodeOpcacheStatus.Keys.Free,
-		"opcache_keys_usedKeys":       nodeOpcacheStatus.Keys.UsedKeys,
-		"opcache_keys_usedScripts":    nodeOpcacheStatus.Keys.UsedScripts,
-		"opcache_keyHits_misses":      nodeOpcacheStatus.KeyHits.Misses,
+	// if rates derived from previous pull, add them
+	if nodeOpcacheStatus.Rates != nil {
+		gaugeNameValueMap["opcache_rates_hits_per_second"] = nodeOpcacheStatus.Rates.HitsPerSecond
+		gaugeNameValueMap["opcache_rates_misses_per_second"] = nodeOpcacheStatus.Rates.MissesPerSecond
+		gaugeNameValueMap["opcache_rates_hit_ratio"] = nodeOpcacheStatus.Rates.HitRatio
+		gaugeNameValueMap["opcache_rates_new_oom_restarts"] = float64(nodeOpcacheStatus.Rates.NewOutOfMemoryRestarts)
+		gaugeNameValueMap["opcache_rates_new_hash_restarts"] = float64(nodeOpcacheStatus.Rates.NewHashRestarts)
+	}
+
+	// if preloading configured, add its statistics
+	if nodeOpcacheStatus.Preload != nil {
+		gaugeNameValueMap["opcache_preload_memory_bytes"] = float64(nodeOpcacheStatus.Preload.MemoryConsumption)
+		gaugeNameValueMap["opcache_preload_scripts"] = float64(len(nodeOpcacheStatus.Preload.Scripts))
+		gaugeNameValueMap["opcache_preload_functions"] = float64(nodeOpcacheStatus.Preload.FunctionsCount)
+		gaugeNameValueMap["opcache_preload_classes"] = float64(nodeOpcacheStatus.Preload.ClassesCount)
+	}
+
+	// if JIT reported, add buffer usage
+	if nodeOpcacheStatus.Jit != nil {
+		gaugeNameValueMap["opcache_jit_buffer_size_bytes"] = float64(nodeOpcacheStatus.Jit.BufferSize)
+		gaugeNameValueMap["opcache_jit_buffer_free_bytes"] = float64(nodeOpcacheStatus.Jit.BufferFree)
+		gaugeNameValueMap["opcache_jit_buffer_used_bytes"] = float64(nodeOpcacheStatus.Jit.BufferUsed)
 	}
 
 	// if APCU enabled, add statistics
 	if nodeApcuStatus.Enabled {
-		gaugeNameValueMap["apcu_memory_free_bytes"] = nodeApcuStatus.SmaInfo.AvailMem
+		gaugeNameValueMap["apcu_memory_free_bytes"] = float64(nodeApcuStatus.SmaInfo.AvailMem)
+
+		if fragmentation := nodeApcuStatus.SmaInfo.Fragmentation; fragmentation != nil {
+			gaugeNameValueMap["apcu_memory_free_blocks"] = float64(fragmentation.FreeBlocksCount)
+			gaugeNameValueMap["apcu_memory_largest_free_block_bytes"] = float64(fragmentation.LargestFreeBlock)
+			gaugeNameValueMap["apcu_memory_fragmentation_percent"] = fragmentation.FragmentationPercentage
+		}
+
+		if nodeApcuStatus.CacheInfo != nil {
+			gaugeNameValueMap["apcu_cache_entries"] = float64(nodeApcuStatus.CacheInfo.NumEntries)
+			gaugeNameValueMap["apcu_cache_hits"] = float64(nodeApcuStatus.CacheInfo.NumHits)
+			gaugeNameValueMap["apcu_cache_misses"] = float64(nodeApcuStatus.CacheInfo.NumMisses)
+			gaugeNameValueMap["apcu_cache_inserts"] = float64(nodeApcuStatus.CacheInfo.NumInserts)
+			gaugeNameValueMap["apcu_cache_expunges"] = float64(nodeApcuStatus.CacheInfo.Expunges)
+			gaugeNameValueMap["apcu_cache_memory_used_bytes"] = float64(nodeApcuStatus.CacheInfo.MemSize)
+			gaugeNameValueMap["apcu_cache_hit_ratio"] = nodeApcuStatus.CacheInfo.HitRatio
+		}
 	}
 
+	// if PHP-FPM status pulled, add statistics
+	if nodeFpmStatus := nodeStatistics.FpmStatistics; nodeFpmStatus != nil {
+		gaugeNameValueMap["fpm_accepted_connections"] = float64(nodeFpmStatus.AcceptedConnections)
+		gaugeNameValueMap["fpm_listen_queue"] = float64(nodeFpmStatus.ListenQueue)
+		gaugeNameValueMap["fpm_max_listen_queue"] = float64(nodeFpmStatus.MaxListenQueue)
+		gaugeNameValueMap["fpm_idle_processes"] = float64(nodeFpmStatus.IdleProcesses)
+		gaugeNameValueMap["fpm_active_processes"] = float64(nodeFpmStatus.ActiveProcesses)
+		gaugeNameValueMap["fpm_total_processes"] = float64(nodeFpmStatus.TotalProcesses)
+		gaugeNameValueMap["fpm_max_children_reached"] = float64(nodeFpmStatus.MaxChildrenReached)
+		gaugeNameValueMap["fpm_slow_requests"] = float64(nodeFpmStatus.SlowRequests)
+	} else {
+		// last pulled values of unavailable status page must not be exported
+		s.deleteNodeGauges(clusterName, groupName, hostName, fpmGaugeNames)
+	}
+
+	s.setNodeGauges(clusterName, groupName, hostName, gaugeNameValueMap)
+}
+
+func (s *PrometheusMetricSender) SendHealth(
+	clusterName string,
+	groupName string,
+	hostName string,
+	health observer.NodeHealth,
+) {
+	gaugeNameValueMap := map[string]float64{
+		"node_healthy":              0,
+		"node_consecutive_failures": float64(health.ConsecutiveFailures),
+	}
+
+	if health.Healthy {
+		gaugeNameValueMap["node_healthy"] = 1
+	}
+
+	// current state of breaker is 1, other states are 0
+	for _, breakerState := range observer.CircuitBreakerStates {
+		gaugeNameValueMap["node_circuit_breaker_state_"+string(breakerState)] = 0
+	}
+	gaugeNameValueMap["node_circuit_breaker_state_"+string(health.CircuitBreaker)] = 1
+
+	s.setNodeGauges(clusterName, groupName, hostName, gaugeNameValueMap)
+}
+
+func (s *PrometheusMetricSender) setNodeGauges(
+	clusterName string,
+	groupName string,
+	hostName string,
+	gaugeNameValueMap map[string]float64,
+) {
+	clusterName = strings.ReplaceAll(clusterName, ".", "-")
+	groupName = strings.ReplaceAll(groupName, ".", "-")
+	hostName = strings.ReplaceAll(hostName, ".", "-")
+
 	for gaugeName, gaugeValue := range gaugeNameValueMap {
 		fullGaugeName := s.buildFullMetricName(gaugeName)
 
@@ -91,7 +273,71 @@ func (s *PrometheusMetricSender) Send(
 					"groupName":   groupName,
 					"hostName":    hostName,
 				},
-			).Set(float64(gaugeValue))
+			).Set(gaugeValue)
+		} else {
+			log.Printf("Gauge %s not declared but used", gaugeName)
+		}
+	}
+}
+
+func (s *PrometheusMetricSender) deleteNodeGauges(
+	clusterName string,
+	groupName string,
+	hostName string,
+	gaugeNames []string,
+) {
+	labels := prometheus.Labels{
+		"clusterName": strings.ReplaceAll(clusterName, ".", "-"),
+		"groupName":   strings.ReplaceAll(groupName, ".", "-"),
+		"hostName":    strings.ReplaceAll(hostName, ".", "-"),
+	}
+
+	for _, gaugeName := range gaugeNames {
+		if gauge, ok := s.gauges[s.buildFullMetricName(gaugeName)]; ok {
+			gauge.Delete(labels)
+		}
+	}
+}
+
+func (s *PrometheusMetricSender) SendSummary(summary analytics.Summary) {
+	clusterName := strings.ReplaceAll(summary.ClusterName, ".", "-")
+	groupName := strings.ReplaceAll(summary.GroupName, ".", "-")
+
+	gaugeNameValueMap := map[string]float64{
+		"opcache_summary_nodes_count":             float64(summary.NodesCount),
+		"opcache_summary_reporting_nodes_count":   float64(summary.ReportingNodesCount),
+		"opcache_summary_memory_total_bytes":      float64(summary.Memory.Total),
+		"opcache_summary_memory_used_bytes":       float64(summary.Memory.Used),
+		"opcache_summary_memory_free_bytes":       float64(summary.Memory.Free),
+		"opcache_summary_memory_wasted_bytes":     float64(summary.Memory.Wasted),
+		"opcache_summary_keyHits_ratio":           summary.KeyHits.HitRatio,
+		"opcache_summary_rates_hits_per_second":   summary.Rates.HitsPerSecond,
+		"opcache_summary_rates_misses_per_second": summary.Rates.MissesPerSecond,
+		"opcache_summary_rates_hit_ratio":         summary.Rates.HitRatio,
+		"opcache_summary_keys_utilisation":        summary.Keys.Utilisation,
+		"opcache_summary_cache_full_nodes":        float64(len(summary.CacheFullNodes)),
+		"opcache_summary_restarts_oom":            float64(summary.Restarts.OutOfMemoryCount),
+		"opcache_summary_restarts_hash":           float64(summary.Restarts.HashCount),
+		"opcache_summary_restarts_manual":         float64(summary.Restarts.ManualCount),
+		"opcache_summary_preload_nodes_count":     float64(summary.Preload.NodesCount),
+		"opcache_summary_preload_memory_bytes":    float64(summary.Preload.MemoryConsumption),
+		"opcache_summary_preload_scripts":         float64(summary.Preload.ScriptsCount),
+	}
+
+	for nodeState, nodesCount := range summary.NodeStates {
+		gaugeNameValueMap["opcache_summary_state_"+string(nodeState)+"_nodes"] = float64(nodesCount)
+	}
+
+	for gaugeName, gaugeValue := range gaugeNameValueMap {
+		fullGaugeName := s.buildFullMetricName(gaugeName)
+
+		if gauge, ok := s.summaryGauges[fullGaugeName]; ok {
+			gauge.With(
+				prometheus.Labels{
+					"clusterName": clusterName,
+					"groupName":   groupName,
+				},
+			).Set(gaugeValue)
 		} else {
 			log.Printf("Gauge %s not declared but used", gaugeName)
 		}
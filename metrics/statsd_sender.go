@@ -1,9 +1,11 @@
 package metrics
 
 import (
+	"math"
 	"strings"
 
 	statsd "github.com/GoMetric/go-statsd-client"
+	"github.com/GoMetric/opcache-dashboard/analytics"
 	"github.com/GoMetric/opcache-dashboard/observer"
 )
 
@@ -23,6 +25,7 @@ func (s *StatsdMetricSender) Send(
 	var metricPrefix = clusterName + "." + groupName + "." + hostName + "."
 
 	nodeOpcacheStatus := nodeStatistics.OpcacheStatistics
+	nodeApcuStatus := nodeStatistics.ApcuStatistics
 
 	metricKeyValueMap := map[string]int{
 		"scripts.count":    len(nodeOpcacheStatus.Scripts),
@@ -35,6 +38,144 @@ func (s *StatsdMetricSender) Send(
 		"keyHits.misses":   nodeOpcacheStatus.KeyHits.Misses,
 	}
 
+	// current state of node is 1, other states are 0
+	for _, nodeState := range observer.NodeStates {
+		metricKeyValueMap["state."+string(nodeState)] = 0
+	}
+	metricKeyValueMap["state."+string(nodeOpcacheStatus.State)] = 1
+
+	// if rates derived from previous pull, add them
+	if nodeOpcacheStatus.Rates != nil {
+		metricKeyValueMap["rates.hitsPerSecond"] = int(math.Round(nodeOpcacheStatus.Rates.HitsPerSecond))
+		metricKeyValueMap["rates.missesPerSecond"] = int(math.Round(nodeOpcacheStatus.Rates.MissesPerSecond))
+		metricKeyValueMap["rates.hitRatioPercent"] = int(nodeOpcacheStatus.Rates.HitRatio * 100)
+		metricKeyValueMap["rates.newOomRestarts"] = nodeOpcacheStatus.Rates.NewOutOfMemoryRestarts
+		metricKeyValueMap["rates.newHashRestarts"] = nodeOpcacheStatus.Rates.NewHashRestarts
+	}
+
+	// if preloading configured, add its statistics
+	if nodeOpcacheStatus.Preload != nil {
+		metricKeyValueMap["preload.memory"] = nodeOpcacheStatus.Preload.MemoryConsumption
+		metricKeyValueMap["preload.scripts"] = len(nodeOpcacheStatus.Preload.Scripts)
+		metricKeyValueMap["preload.functions"] = nodeOpcacheStatus.Preload.FunctionsCount
+		metricKeyValueMap["preload.classes"] = nodeOpcacheStatus.Preload.ClassesCount
+	}
+
+	// if JIT reported, add buffer usage
+	if nodeOpcacheStatus.Jit != nil {
+		metricKeyValueMap["jit.bufferSize"] = nodeOpcacheStatus.Jit.BufferSize
+		metricKeyValueMap["jit.bufferFree"] = nodeOpcacheStatus.Jit.BufferFree
+		metricKeyValueMap["jit.bufferUsed"] = nodeOpcacheStatus.Jit.BufferUsed
+	}
+
+	// if APCU enabled, add statistics
+	if nodeApcuStatus.Enabled {
+		metricKeyValueMap["apcu.memory.free"] = nodeApcuStatus.SmaInfo.AvailMem
+
+		if fragmentation := nodeApcuStatus.SmaInfo.Fragmentation; fragmentation != nil {
+			metricKeyValueMap["apcu.memory.freeBlocks"] = fragmentation.FreeBlocksCount
+			metricKeyValueMap["apcu.memory.largestFreeBlock"] = fragmentation.LargestFreeBlock
+			metricKeyValueMap["apcu.memory.fragmentationPercent"] = int(math.Round(fragmentation.FragmentationPercentage))
+		}
+
+		if nodeApcuStatus.CacheInfo != nil {
+			metricKeyValueMap["apcu.cache.entries"] = nodeApcuStatus.CacheInfo.NumEntries
+			metricKeyValueMap["apcu.cache.hits"] = nodeApcuStatus.CacheInfo.NumHits
+			metricKeyValueMap["apcu.cache.misses"] = nodeApcuStatus.CacheInfo.NumMisses
+			metricKeyValueMap["apcu.cache.inserts"] = nodeApcuStatus.CacheInfo.NumInserts
+			metricKeyValueMap["apcu.cache.expunges"] = nodeApcuStatus.CacheInfo.Expunges
+			metricKeyValueMap["apcu.cache.memoryUsed"] = nodeApcuStatus.CacheInfo.MemSize
+			metricKeyValueMap["apcu.cache.hitRatioPercent"] = int(nodeApcuStatus.CacheInfo.HitRatio * 100)
+		}
+	}
+
+	// if PHP-FPM status pulled, add statistics
+	if nodeFpmStatus := nodeStatistics.FpmStatistics; nodeFpmStatus != nil {
+		metricKeyValueMap["fpm.acceptedConnections"] = nodeFpmStatus.AcceptedConnections
+		metricKeyValueMap["fpm.listenQueue"] = nodeFpmStatus.ListenQueue
+		metricKeyValueMap["fpm.maxListenQueue"] = nodeFpmStatus.MaxListenQueue
+		metricKeyValueMap["fpm.idleProcesses"] = nodeFpmStatus.IdleProcesses
+		metricKeyValueMap["fpm.activeProcesses"] = nodeFpmStatus.ActiveProcesses
+		metricKeyValueMap["fpm.totalProcesses"] = nodeFpmStatus.TotalProcesses
+		metricKeyValueMap["fpm.maxChildrenReached"] = nodeFpmStatus.MaxChildrenReached
+		metricKeyValueMap["fpm.slowRequests"] = nodeFpmStatus.SlowRequests
+	}
+
+	for metricKey, metricValue := range metricKeyValueMap {
+		s.StatsdClient.Gauge(
+			metricPrefix+metricKey,
+			metricValue,
+		)
+	}
+}
+
+func (s *StatsdMetricSender) SendHealth(
+	clusterName string,
+	groupName string,
+	hostName string,
+	health observer.NodeHealth,
+) {
+	clusterName = strings.ReplaceAll(clusterName, ".", "-")
+	groupName = strings.ReplaceAll(groupName, ".", "-")
+	hostName = strings.ReplaceAll(hostName, ".", "-")
+	var metricPrefix = clusterName + "." + groupName + "." + hostName + "."
+
+	metricKeyValueMap := map[string]int{
+		"health.healthy":             0,
+		"health.consecutiveFailures": health.ConsecutiveFailures,
+	}
+
+	if health.Healthy {
+		metricKeyValueMap["health.healthy"] = 1
+	}
+
+	// current state of breaker is 1, other states are 0
+	for _, breakerState := range observer.CircuitBreakerStates {
+		metricKeyValueMap["health.circuitBreaker."+string(breakerState)] = 0
+	}
+	metricKeyValueMap["health.circuitBreaker."+string(health.CircuitBreaker)] = 1
+
+	for metricKey, metricValue := range metricKeyValueMap {
+		s.StatsdClient.Gauge(
+			metricPrefix+metricKey,
+			metricValue,
+		)
+	}
+}
+
+func (s *StatsdMetricSender) SendSummary(summary analytics.Summary) {
+	// cluster summary tracked as "{cluster}._summary.*", group summary as "{cluster}.{group}._summary.*"
+	var metricPrefix = strings.ReplaceAll(summary.ClusterName, ".", "-") + "."
+	if summary.GroupName != "" {
+		metricPrefix += strings.ReplaceAll(summary.GroupName, ".", "-") + "."
+	}
+	metricPrefix += "_summary."
+
+	metricKeyValueMap := map[string]int{
+		"nodes.count":             summary.NodesCount,
+		"nodes.reporting":         summary.ReportingNodesCount,
+		"nodes.cacheFull":         len(summary.CacheFullNodes),
+		"memory.total":            summary.Memory.Total,
+		"memory.used":             summary.Memory.Used,
+		"memory.free":             summary.Memory.Free,
+		"memory.wasted":           summary.Memory.Wasted,
+		"keyHits.ratioPercent":    int(summary.KeyHits.HitRatio * 100),
+		"rates.hitsPerSecond":     int(math.Round(summary.Rates.HitsPerSecond)),
+		"rates.missesPerSecond":   int(math.Round(summary.Rates.MissesPerSecond)),
+		"rates.hitRatioPercent":   int(summary.Rates.HitRatio * 100),
+		"keys.utilisationPercent": int(summary.Keys.Utilisation * 100),
+		"restarts.oom":            summary.Restarts.OutOfMemoryCount,
+		"restarts.hash":           summary.Restarts.HashCount,
+		"restarts.manual":         summary.Restarts.ManualCount,
+		"preload.nodes":           summary.Preload.NodesCount,
+		"preload.memory":          summary.Preload.MemoryConsumption,
+		"preload.scripts":         summary.Preload.ScriptsCount,
+	}
+
+	for nodeState, nodesCount := range summary.NodeStates {
+		metricKeyValueMap["nodes."+string(nodeState)] = nodesCount
+	}
+
 	for metricKey, metricValue := range metricKeyValueMap {
 		s.StatsdClient.Gauge(
 			metricPrefix+metricKey,
@@ -2,20 +2,35 @@ package observer
 
 type ClustersApcuStatuses map[string]map[string]map[string]NodeApcuStatus
 
-type NodeApcuStatus struct{
-	Enabled bool
-	SmaInfo *NodeApcuSmaInfo
-	Settings *map[string]NodeApcuSetting
+type NodeApcuStatus struct {
+	Enabled   bool
+	SmaInfo   *NodeApcuSmaInfo
+	CacheInfo *NodeApcuCacheInfo
+	Settings  *map[string]NodeApcuSetting
 }
 
 type NodeApcuSmaInfo struct {
-	NumSeg     int
-	SegSize    int // Total memory
-	AvailMem   int // Free memory
-	//BlockLists [][]struct {
-	//	Size   int
-	//	Offset int
-	//}
+	NumSeg   int
+	SegSize  int // Total memory
+	AvailMem int // Free memory
+	// Fragmentation of free memory of all segments and of every segment, nil when block lists not reported
+	Fragmentation         *NodeApcuFragmentation
+	SegmentsFragmentation []NodeApcuFragmentation
+}
+
+// NodeApcuCacheInfo represents statistics of APCu user cache, see apcu_cache_info()
+type NodeApcuCacheInfo struct {
+	NumSlots   int    // num_slots
+	TTL        int    // ttl
+	NumEntries int    // num_entries
+	NumHits    int    // num_hits
+	NumMisses  int    // num_misses
+	NumInserts int    // num_inserts
+	Expunges   int    // expunges
+	StartTime  int64  // start_time
+	MemSize    int    // mem_size, memory used by entries
+	MemoryType string // memory_type
+	HitRatio   float64
 }
 
 type NodeApcuSetting struct {
@@ -0,0 +1,121 @@
+package observer
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Versions of protocol between observer and agent
+const (
+	// LegacyAgentProtocolVersion is version of agents which do not report protocol section
+	LegacyAgentProtocolVersion = 1
+	// AgentProtocolVersion is latest version supported by observer, requested from every agent
+	AgentProtocolVersion = 2
+)
+
+// Commands of agent, passed in "command" query parameter
+const (
+	AgentCommandStatus       = "status"
+	AgentCommandReset        = "reset"
+	AgentCommandInvalidate   = "invalidate"
+	AgentCommandCapabilities = "capabilities"
+	AgentCommandApcuKeys     = "apcuKeys"
+	AgentCommandApcuDelete   = "apcuDelete"
+	AgentCommandApcuClear    = "apcuClear"
+)
+
+// Sections of agent status response
+const (
+	AgentSectionConfiguration = "configuration"
+	AgentSectionStatus        = "status"
+	AgentSectionScripts       = "scripts"
+	AgentSectionApcu          = "apcu"
+)
+
+// ErrCommandNotSupported returned when agent of node does not declare requested command
+var ErrCommandNotSupported = errors.New("Command not supported by agent, update agent script on node")
+
+// AgentInfo describes protocol and capabilities reported by agent of node
+type AgentInfo struct {
+	ProtocolVersion int
+	Commands        []string
+	Sections        []string
+	// Agent speaks older protocol than observer and should be updated
+	Outdated bool
+}
+
+// legacyAgentInfo describes capabilities of agents which do not report them
+func legacyAgentInfo() AgentInfo {
+	return AgentInfo{
+		ProtocolVersion: LegacyAgentProtocolVersion,
+		Commands:        []string{AgentCommandStatus, AgentCommandReset, AgentCommandInvalidate},
+		Sections:        []string{AgentSectionConfiguration, AgentSectionStatus, AgentSectionScripts, AgentSectionApcu},
+		Outdated:        true,
+	}
+}
+
+// SupportsCommand checks if agent declares command
+func (a AgentInfo) SupportsCommand(command string) bool {
+	return containsString(a.Commands, command)
+}
+
+// SupportsSection checks if agent may return section of status response
+func (a AgentInfo) SupportsSection(section string) bool {
+	return containsString(a.Sections, section)
+}
+
+// buildAgentQuery adds requested protocol version to query parameters of agent request
+func buildAgentQuery(parameters url.Values) string {
+	if parameters == nil {
+		parameters = url.Values{}
+	}
+
+	parameters.Set("protocol", strconv.Itoa(AgentProtocolVersion))
+
+	return parameters.Encode()
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAgentsInfo returns protocol and capabilities of agents of pulled nodes
+// Struct: {clusterName}.{groupName}.{nodeName} => AgentInfo
+func (o *Observer) GetAgentsInfo() map[string]map[string]map[string]AgentInfo {
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	agentsInfo := map[string]map[string]map[string]AgentInfo{}
+	for nodeKey, agentInfo := range o.agentsInfo {
+		if _, ok := agentsInfo[nodeKey.clusterName]; !ok {
+			agentsInfo[nodeKey.clusterName] = map[string]map[string]AgentInfo{}
+		}
+
+		if _, ok := agentsInfo[nodeKey.clusterName][nodeKey.groupName]; !ok {
+			agentsInfo[nodeKey.clusterName][nodeKey.groupName] = map[string]AgentInfo{}
+		}
+
+		agentsInfo[nodeKey.clusterName][nodeKey.groupName][nodeKey.hostName] = agentInfo
+	}
+
+	return agentsInfo
+}
+
+// getAgentInfo returns capabilities of node agent, which assumed legacy until node pulled
+func (o *Observer) getAgentInfo(clusterName string, groupName string, hostName string) AgentInfo {
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	if agentInfo, ok := o.agentsInfo[nodeKey{clusterName, groupName, hostName}]; ok {
+		return agentInfo
+	}
+
+	return legacyAgentInfo()
+}
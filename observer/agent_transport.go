@@ -0,0 +1,182 @@
+package observer
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+// ErrFastCGINotConfigured returned when group uses FastCGI transport without FastCGI settings
+var ErrFastCGINotConfigured = errors.New("FastCGI transport not configured for group")
+
+// AgentTransportInterface delivers requests of observer to agents of nodes
+type AgentTransportInterface interface {
+	// FetchStatus requests opcache and apcu status of node, optionally with script list
+	FetchStatus(groupConfig configuration.GroupConfig, host string, includeScripts bool) (*http.Response, error)
+	// ExecuteCommand sends command with parameters to agent of node
+	ExecuteCommand(
+		groupConfig configuration.GroupConfig,
+		host string,
+		command string,
+		parameters url.Values,
+	) (*http.Response, error)
+}
+
+// HTTPAgentTransport sends GET requests to agent served by web server
+type HTTPAgentTransport struct {
+	client *http.Client
+}
+
+func NewHTTPAgentTransport(client *http.Client) *HTTPAgentTransport {
+	return &HTTPAgentTransport{
+		client: client,
+	}
+}
+
+func (t *HTTPAgentTransport) FetchStatus(
+	groupConfig configuration.GroupConfig,
+	host string,
+	includeScripts bool,
+) (*http.Response, error) {
+	return t.request(groupConfig, host, buildStatusParameters(includeScripts))
+}
+
+func (t *HTTPAgentTransport) ExecuteCommand(
+	groupConfig configuration.GroupConfig,
+	host string,
+	command string,
+	parameters url.Values,
+) (*http.Response, error) {
+	return t.request(groupConfig, host, buildCommandParameters(command, parameters))
+}
+
+func (t *HTTPAgentTransport) request(
+	groupConfig configuration.GroupConfig,
+	host string,
+	parameters url.Values,
+) (*http.Response, error) {
+	agentURL := buildPullAgentUrl(groupConfig.UrlPattern, host) + "?" + buildAgentQuery(parameters)
+
+	request, err := http.NewRequest("GET", agentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupConfig.BasicAuthCredentials != nil {
+		request.SetBasicAuth(groupConfig.BasicAuthCredentials.User, groupConfig.BasicAuthCredentials.Password)
+	}
+
+	return t.client.Do(request)
+}
+
+// FastCGIAgentTransport executes agent script directly by PHP-FPM
+type FastCGIAgentTransport struct {
+}
+
+func NewFastCGIAgentTransport() *FastCGIAgentTransport {
+	return &FastCGIAgentTransport{}
+}
+
+func (t *FastCGIAgentTransport) FetchStatus(
+	groupConfig configuration.GroupConfig,
+	host string,
+	includeScripts bool,
+) (*http.Response, error) {
+	return t.request(groupConfig, host, buildStatusParameters(includeScripts))
+}
+
+func (t *FastCGIAgentTransport) ExecuteCommand(
+	groupConfig configuration.GroupConfig,
+	host string,
+	command string,
+	parameters url.Values,
+) (*http.Response, error) {
+	return t.request(groupConfig, host, buildCommandParameters(command, parameters))
+}
+
+func (t *FastCGIAgentTransport) request(
+	groupConfig configuration.GroupConfig,
+	host string,
+	parameters url.Values,
+) (*http.Response, error) {
+	if groupConfig.FastCGI == nil {
+		return nil, ErrFastCGINotConfigured
+	}
+
+	return DoFastCGIRequest(
+		FastCGIRequest{
+			Address:    buildPullAgentUrl(groupConfig.FastCGI.AddressPattern, host),
+			ScriptPath: groupConfig.FastCGI.ScriptPath,
+			Query:      buildAgentQuery(parameters),
+		},
+		agentRequestTimeout,
+	)
+}
+
+// groupAgentTransport delivers requests over transport configured for group
+type groupAgentTransport struct {
+	httpTransport    AgentTransportInterface
+	fastCGITransport AgentTransportInterface
+}
+
+func newGroupAgentTransport(client *http.Client) *groupAgentTransport {
+	return &groupAgentTransport{
+		httpTransport:    NewHTTPAgentTransport(client),
+		fastCGITransport: NewFastCGIAgentTransport(),
+	}
+}
+
+func (t *groupAgentTransport) FetchStatus(
+	groupConfig configuration.GroupConfig,
+	host string,
+	includeScripts bool,
+) (*http.Response, error) {
+	return t.transportOf(groupConfig).FetchStatus(groupConfig, host, includeScripts)
+}
+
+func (t *groupAgentTransport) ExecuteCommand(
+	groupConfig configuration.GroupConfig,
+	host string,
+	command string,
+	parameters url.Values,
+) (*http.Response, error) {
+	return t.transportOf(groupConfig).ExecuteCommand(groupConfig, host, command, parameters)
+}
+
+func (t *groupAgentTransport) transportOf(groupConfig configuration.GroupConfig) AgentTransportInterface {
+	if groupConfig.Transport == configuration.AgentTransportFastCGI {
+		return t.fastCGITransport
+	}
+
+	return t.httpTransport
+}
+
+func buildStatusParameters(includeScripts bool) url.Values {
+	parameters := url.Values{}
+	if includeScripts {
+		parameters.Set("scripts", "1")
+	}
+
+	return parameters
+}
+
+// buildCommandParameters copies parameters, so passed ones not modified
+func buildCommandParameters(command string, parameters url.Values) url.Values {
+	commandParameters := url.Values{}
+	for name, values := range parameters {
+		commandParameters[name] = append([]string{}, values...)
+	}
+
+	commandParameters.Set("command", command)
+
+	return commandParameters
+}
+
+func buildPullAgentUrl(urlPattern string, host string) string {
+	urlPatternReplacer := strings.NewReplacer("{host}", host)
+
+	return urlPatternReplacer.Replace(urlPattern)
+}
@@ -0,0 +1,70 @@
+package observer
+
+// Upper bounds of free block size buckets, last bucket holds larger blocks
+var apcuFreeBlockSizeBuckets = []int{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// NodeApcuFragmentation describes how free APCu shared memory split into blocks.
+// Entry can be stored only in single free block, so plenty of available memory
+// still leads to evictions when it fragmented into small blocks.
+type NodeApcuFragmentation struct {
+	FreeBlocksCount  int
+	FreeMemory       int
+	LargestFreeBlock int
+	// Share of free memory outside of largest free block
+	FragmentationPercentage float64
+	FreeBlocksHistogram     []NodeApcuFreeBlocksBucket
+}
+
+// NodeApcuFreeBlocksBucket counts free blocks with size up to MaxSize
+type NodeApcuFreeBlocksBucket struct {
+	MaxSize int // 0 for last bucket of largest blocks
+	Count   int
+	Memory  int
+}
+
+type apcuFreeBlock struct {
+	Size   int `json:"size"`
+	Offset int `json:"offset"`
+}
+
+// analyzeApcuFragmentation describes free blocks of shared memory segment or of all segments together
+func analyzeApcuFragmentation(freeBlocks []apcuFreeBlock) NodeApcuFragmentation {
+	fragmentation := NodeApcuFragmentation{
+		FreeBlocksHistogram: make([]NodeApcuFreeBlocksBucket, len(apcuFreeBlockSizeBuckets)+1),
+	}
+
+	for bucketIndex, maxSize := range apcuFreeBlockSizeBuckets {
+		fragmentation.FreeBlocksHistogram[bucketIndex].MaxSize = maxSize
+	}
+
+	for _, freeBlock := range freeBlocks {
+		fragmentation.FreeBlocksCount++
+		fragmentation.FreeMemory += freeBlock.Size
+		fragmentation.LargestFreeBlock = maxInt(fragmentation.LargestFreeBlock, freeBlock.Size)
+
+		bucketIndex := len(apcuFreeBlockSizeBuckets)
+		for index, maxSize := range apcuFreeBlockSizeBuckets {
+			if freeBlock.Size <= maxSize {
+				bucketIndex = index
+				break
+			}
+		}
+
+		fragmentation.FreeBlocksHistogram[bucketIndex].Count++
+		fragmentation.FreeBlocksHistogram[bucketIndex].Memory += freeBlock.Size
+	}
+
+	if fragmentation.FreeMemory > 0 {
+		fragmentation.FragmentationPercentage = 100 * float64(fragmentation.FreeMemory-fragmentation.LargestFreeBlock) / float64(fragmentation.FreeMemory)
+	}
+
+	return fragmentation
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
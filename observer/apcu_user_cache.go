@@ -0,0 +1,293 @@
+package observer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+// ErrNodeNotFound returned when node not defined in configuration
+var ErrNodeNotFound = errors.New("Node not found")
+
+// ApcuKeysMaxLimit is maximum count of APCu user cache entries on one page
+const ApcuKeysMaxLimit = 1000
+
+// ApcuUserCacheEntry describes entry of APCu user cache
+type ApcuUserCacheEntry struct {
+	Key              string
+	Size             int
+	Hits             int
+	TTL              int
+	CreationTime     int64
+	AccessTime       int64
+	ModificationTime int64
+}
+
+// ApcuUserCacheKeysPage is page of APCu user cache entries of node
+type ApcuUserCacheKeysPage struct {
+	Total   int
+	Offset  int
+	Entries []ApcuUserCacheEntry
+}
+
+// ApcuCommandResult describes outcome of APCu command on single node
+type ApcuCommandResult struct {
+	Success      bool
+	Error        string
+	DeletedCount int
+}
+
+type agentCommandResponse struct {
+	Error *string `json:"error"`
+}
+
+type agentApcuKeysResponse struct {
+	Total   int `json:"total"`
+	Entries []struct {
+		Key              string  `json:"key"`
+		Size             float64 `json:"size"`
+		Hits             float64 `json:"hits"`
+		TTL              int     `json:"ttl"`
+		CreationTime     int64   `json:"creation_time"`
+		AccessTime       int64   `json:"access_time"`
+		ModificationTime int64   `json:"modification_time"`
+	} `json:"entries"`
+}
+
+type agentApcuDeleteResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// ListApcuKeys returns page of APCu user cache entries of node, optionally filtered by key prefix
+func (o *Observer) ListApcuKeys(
+	clusterName string,
+	groupName string,
+	hostName string,
+	prefix string,
+	offset int,
+	limit int,
+) (*ApcuUserCacheKeysPage, error) {
+	if limit <= 0 || limit > ApcuKeysMaxLimit {
+		limit = ApcuKeysMaxLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	agentResponse := agentApcuKeysResponse{}
+
+	err := o.sendAgentCommand(
+		clusterName,
+		groupName,
+		hostName,
+		AgentCommandApcuKeys,
+		url.Values{
+			"prefix": {prefix},
+			"offset": {strconv.Itoa(offset)},
+			"limit":  {strconv.Itoa(limit)},
+		},
+		&agentResponse,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := ApcuUserCacheKeysPage{
+		Total:   agentResponse.Total,
+		Offset:  offset,
+		Entries: []ApcuUserCacheEntry{},
+	}
+
+	for _, entry := range agentResponse.Entries {
+		page.Entries = append(page.Entries, ApcuUserCacheEntry{
+			Key:              entry.Key,
+			Size:             int(entry.Size),
+			Hits:             int(entry.Hits),
+			TTL:              entry.TTL,
+			CreationTime:     entry.CreationTime,
+			AccessTime:       entry.AccessTime,
+			ModificationTime: entry.ModificationTime,
+		})
+	}
+
+	return &page, nil
+}
+
+// DeleteApcuKeys deletes APCu user cache entry by exact key or all entries with key prefix
+// on single node, or on all nodes of group when host name empty. Returns result of every node.
+func (o *Observer) DeleteApcuKeys(
+	clusterName string,
+	groupName string,
+	hostName string,
+	key string,
+	prefix string,
+) (map[string]ApcuCommandResult, error) {
+	if key == "" && prefix == "" {
+		return nil, errors.New("Key or prefix must be defined")
+	}
+
+	return o.sendApcuCommandToNodes(
+		clusterName,
+		groupName,
+		hostName,
+		func(nodeHostName string) ApcuCommandResult {
+			agentResponse := agentApcuDeleteResponse{}
+
+			err := o.sendAgentCommand(
+				clusterName,
+				groupName,
+				nodeHostName,
+				AgentCommandApcuDelete,
+				url.Values{"key": {key}, "prefix": {prefix}},
+				&agentResponse,
+			)
+
+			return newApcuCommandResult(err, agentResponse.Deleted)
+		},
+	)
+}
+
+// ClearApcuCache clears APCu user cache on single node, or on all nodes of group when host name empty.
+// Returns result of every node.
+func (o *Observer) ClearApcuCache(
+	clusterName string,
+	groupName string,
+	hostName string,
+) (map[string]ApcuCommandResult, error) {
+	return o.sendApcuCommandToNodes(
+		clusterName,
+		groupName,
+		hostName,
+		func(nodeHostName string) ApcuCommandResult {
+			err := o.sendAgentCommand(clusterName, groupName, nodeHostName, AgentCommandApcuClear, nil, nil)
+
+			return newApcuCommandResult(err, 0)
+		},
+	)
+}
+
+// sendApcuCommandToNodes runs command on node or on every node of group, then refreshes statistics of changed nodes in background
+func (o *Observer) sendApcuCommandToNodes(
+	clusterName string,
+	groupName string,
+	hostName string,
+	sendCommand func(hostName string) ApcuCommandResult,
+) (map[string]ApcuCommandResult, error) {
+	groupConfig, ok := o.Clusters[clusterName].Groups[groupName]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	hostNames := groupConfig.Hosts
+	if hostName != "" {
+		if !containsString(groupConfig.Hosts, hostName) {
+			return nil, ErrNodeNotFound
+		}
+
+		hostNames = []string{hostName}
+	}
+
+	results := map[string]ApcuCommandResult{}
+	changedHostNames := []string{}
+
+	for _, nodeHostName := range hostNames {
+		results[nodeHostName] = sendCommand(nodeHostName)
+
+		if results[nodeHostName].Success {
+			changedHostNames = append(changedHostNames, nodeHostName)
+		}
+	}
+
+	// pulling every node may take longer than request timeout, so statistics refreshed in background
+	if len(changedHostNames) > 0 {
+		go o.pullNodes(groupConfig, clusterName, groupName, changedHostNames)
+	}
+
+	return results, nil
+}
+
+// pullNodes pulls statistics of passed nodes without script lists and notifies listeners
+func (o *Observer) pullNodes(
+	groupConfig configuration.GroupConfig,
+	clusterName string,
+	groupName string,
+	hostNames []string,
+) {
+	retryDeadline := o.unscheduledPullRetryDeadline(clusterName, groupName)
+
+	for _, hostName := range hostNames {
+		o.pullAgent(groupConfig, clusterName, groupName, hostName, false, retryDeadline)
+	}
+
+	o.notifyPullListeners()
+}
+
+func newApcuCommandResult(err error, deletedCount int) ApcuCommandResult {
+	if err != nil {
+		return ApcuCommandResult{Error: err.Error()}
+	}
+
+	return ApcuCommandResult{Success: true, DeletedCount: deletedCount}
+}
+
+// sendAgentCommand sends command to agent of node and decodes its response to target, if defined
+func (o *Observer) sendAgentCommand(
+	clusterName string,
+	groupName string,
+	hostName string,
+	command string,
+	parameters url.Values,
+	target interface{},
+) error {
+	groupConfig, ok := o.Clusters[clusterName].Groups[groupName]
+	if !ok || !containsString(groupConfig.Hosts, hostName) {
+		return ErrNodeNotFound
+	}
+
+	if !o.getAgentInfo(clusterName, groupName, hostName).SupportsCommand(command) {
+		return ErrCommandNotSupported
+	}
+
+	log.Printf("Sending command %s to node %s", command, hostName)
+
+	response, err := o.getAgentTransport().ExecuteCommand(groupConfig, hostName, command, parameters)
+	if err != nil {
+		return err
+	}
+
+	defer closeResponseBody(response.Body)
+
+	body, err := io.ReadAll(newLimitedReader(response.Body, o.getMaxResponseSize()))
+	if err != nil {
+		return err
+	}
+
+	// agent describes failure in error field
+	commandResponse := agentCommandResponse{}
+	if err := json.Unmarshal(body, &commandResponse); err == nil && commandResponse.Error != nil {
+		return fmt.Errorf("Node %s returned error: %s", hostName, *commandResponse.Error)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Observable node return error %s", response.Status)
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("Invalid response of node %s: %w", hostName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package observer
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// CircuitBreakerState describes whether node polled
+type CircuitBreakerState string
+
+// States of circuit breaker of node
+const (
+	// Node polled on every pull
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+	// Node failed repeatedly, pulls skipped until NextPullTime
+	CircuitBreakerOpen CircuitBreakerState = "open"
+	// Single trial pull of node in progress after open interval passed
+	CircuitBreakerHalfOpen CircuitBreakerState = "halfOpen"
+)
+
+// CircuitBreakerStates lists all states of circuit breaker
+var CircuitBreakerStates = []CircuitBreakerState{
+	CircuitBreakerClosed,
+	CircuitBreakerOpen,
+	CircuitBreakerHalfOpen,
+}
+
+// beginNodePull tells whether node may be pulled now. Pull of node with open breaker
+// allowed only after open interval as single trial, which must not be retried.
+// Other pulls skipped while trial in progress.
+func (o *Observer) beginNodePull(key nodeKey) (allowed bool, isTrial bool) {
+	o.statusesMutex.Lock()
+	defer o.statusesMutex.Unlock()
+
+	health, wasPulled := o.nodesHealth[key]
+	if health.CircuitBreaker == CircuitBreakerHalfOpen {
+		return false, false
+	}
+
+	if !wasPulled || health.CircuitBreaker != CircuitBreakerOpen {
+		return true, false
+	}
+
+	if time.Now().Before(health.NextPullTime) {
+		return false, false
+	}
+
+	health.CircuitBreaker = CircuitBreakerHalfOpen
+	o.nodesHealth[key] = health
+
+	return true, true
+}
+
+// Count of pull intervals of group node kept aside when open interval not configured
+const circuitBreakerOpenPullIntervals = 2
+
+// applyCircuitBreaker moves breaker of node to next state by result of pull
+func (o *Observer) applyCircuitBreaker(key nodeKey, health *NodeHealth, previousHealth NodeHealth, now time.Time) {
+	hostName := key.hostName
+
+	// open interval shorter than pull interval would let every scheduled pull through
+	openInterval := time.Duration(o.CircuitBreaker.OpenIntervalSeconds) * time.Second
+	if openInterval <= 0 {
+		openInterval = circuitBreakerOpenPullIntervals * o.pullIntervalOf(key.clusterName, key.groupName)
+	}
+
+	if health.Healthy || o.CircuitBreaker.FailureThreshold <= 0 {
+		if previousHealth.CircuitBreaker == CircuitBreakerHalfOpen {
+			log.Printf("Circuit breaker of node %s closed", hostName)
+		}
+
+		health.CircuitBreaker = CircuitBreakerClosed
+
+		return
+	}
+
+	switch {
+	case previousHealth.CircuitBreaker == CircuitBreakerHalfOpen && previousHealth.openInterval > 0:
+		// trial pull failed, keep node aside longer
+		health.openInterval = previousHealth.openInterval * 2
+	case health.ConsecutiveFailures >= o.CircuitBreaker.FailureThreshold:
+		health.openInterval = openInterval
+	default:
+		health.CircuitBreaker = CircuitBreakerClosed
+
+		return
+	}
+
+	maxOpenInterval := time.Duration(o.CircuitBreaker.MaxOpenIntervalSeconds) * time.Second
+	if maxOpenInterval < openInterval {
+		maxOpenInterval = openInterval
+	}
+
+	if maxOpenInterval > 0 && health.openInterval > maxOpenInterval {
+		health.openInterval = maxOpenInterval
+	}
+
+	// spread trial pulls of nodes opened at same time
+	jitter := time.Duration(rand.Int63n(int64(health.openInterval/10) + 1))
+
+	health.CircuitBreaker = CircuitBreakerOpen
+	health.NextPullTime = now.Add(health.openInterval + jitter)
+
+	log.Printf(
+		"Circuit breaker of node %s opened after %d failed pulls, next pull at %s",
+		hostName,
+		health.ConsecutiveFailures,
+		health.NextPullTime.Format(time.RFC3339),
+	)
+}
@@ -0,0 +1,115 @@
+package observer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+func TestBeginNodePull(t *testing.T) {
+	testCases := []struct {
+		name            string
+		health          *NodeHealth
+		expectedAllowed bool
+		expectedTrial   bool
+	}{
+		{name: "never pulled", expectedAllowed: true},
+		{name: "closed", health: &NodeHealth{CircuitBreaker: CircuitBreakerClosed}, expectedAllowed: true},
+		{
+			name:   "open",
+			health: &NodeHealth{CircuitBreaker: CircuitBreakerOpen, NextPullTime: time.Now().Add(time.Minute)},
+		},
+		{
+			name:            "open interval passed",
+			health:          &NodeHealth{CircuitBreaker: CircuitBreakerOpen, NextPullTime: time.Now().Add(-time.Second)},
+			expectedAllowed: true,
+			expectedTrial:   true,
+		},
+		{
+			name:   "trial pull in progress",
+			health: &NodeHealth{CircuitBreaker: CircuitBreakerHalfOpen, NextPullTime: time.Now().Add(-time.Second)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			o, _ := newTestObserver(t, "web1")
+			key := nodeKey{testClusterName, testGroupName, "web1"}
+
+			o.nodesHealth = map[nodeKey]NodeHealth{}
+			if testCase.health != nil {
+				o.nodesHealth[key] = *testCase.health
+			}
+
+			allowed, isTrial := o.beginNodePull(key)
+			if allowed != testCase.expectedAllowed || isTrial != testCase.expectedTrial {
+				t.Errorf("got allowed %v, trial %v", allowed, isTrial)
+			}
+		})
+	}
+}
+
+func TestBeginNodePullSingleTrial(t *testing.T) {
+	o, _ := newTestObserver(t, "web1")
+	key := nodeKey{testClusterName, testGroupName, "web1"}
+	o.nodesHealth = map[nodeKey]NodeHealth{
+		key: {CircuitBreaker: CircuitBreakerOpen, NextPullTime: time.Now().Add(-time.Second)},
+	}
+
+	if allowed, isTrial := o.beginNodePull(key); !allowed || !isTrial {
+		t.Fatalf("first pull: got allowed %v, trial %v", allowed, isTrial)
+	}
+
+	// concurrent pull, e.g. manual refresh during scheduled trial
+	if allowed, _ := o.beginNodePull(key); allowed {
+		t.Error("second pull allowed while trial pull in progress")
+	}
+}
+
+func TestCircuitBreakerOpenInterval(t *testing.T) {
+	testCases := []struct {
+		name             string
+		circuitBreaker   configuration.CircuitBreakerConfig
+		expectedInterval time.Duration
+	}{
+		{
+			name:             "configured",
+			circuitBreaker:   configuration.CircuitBreakerConfig{FailureThreshold: 1, OpenIntervalSeconds: 30},
+			expectedInterval: 30 * time.Second,
+		},
+		{
+			name:             "derived from pull interval of group",
+			circuitBreaker:   configuration.CircuitBreakerConfig{FailureThreshold: 1},
+			expectedInterval: 2 * time.Minute,
+		},
+		{
+			name:             "derived not limited by lower maximum",
+			circuitBreaker:   configuration.CircuitBreakerConfig{FailureThreshold: 1, MaxOpenIntervalSeconds: 60},
+			expectedInterval: 2 * time.Minute,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			o, _ := newTestObserver(t, "web1")
+			o.CircuitBreaker = testCase.circuitBreaker
+			o.defaultPullInterval = time.Minute
+
+			failedAt := time.Now()
+			o.updateNodeHealth(testClusterName, testGroupName, "web1", errors.New("connection refused"))
+
+			health := o.GetNodesHealth()[testClusterName][testGroupName]["web1"]
+			if health.CircuitBreaker != CircuitBreakerOpen {
+				t.Fatalf("got %s circuit breaker", health.CircuitBreaker)
+			}
+
+			// jitter adds up to tenth of interval
+			openInterval := health.NextPullTime.Sub(failedAt)
+			if openInterval < testCase.expectedInterval || openInterval > testCase.expectedInterval*11/10+time.Second {
+				t.Errorf("got open interval %s, want %s", openInterval, testCase.expectedInterval)
+			}
+		})
+	}
+}
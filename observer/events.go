@@ -0,0 +1,129 @@
+package observer
+
+import "time"
+
+// Types of observer events
+const (
+	EventNodeUpdated       = "nodeUpdated"
+	EventNodeHealthChanged = "nodeHealthChanged"
+	EventResetCompleted    = "resetCompleted"
+)
+
+// Event describes change produced by observer
+type Event struct {
+	Type        string
+	ClusterName string
+	GroupName   string
+	HostName    string
+	Data        interface{}
+}
+
+// EventListener receives observer events. Must not block, because called from pulling routine.
+type EventListener func(event Event)
+
+// NodeUpdate is data of node update event. Script list is omitted to keep event compact.
+type NodeUpdate struct {
+	OpcacheStatistics NodeOpcacheStatus
+	ApcuStatistics    NodeApcuStatus
+	FpmStatistics     *NodeFpmStatus
+	ScriptsCount      int
+}
+
+// NodeHealth describes result of last pull of node
+type NodeHealth struct {
+	Healthy             bool
+	Error               string
+	LastPullTime        time.Time
+	LastSuccessTime     time.Time
+	ConsecutiveFailures int
+	CircuitBreaker      CircuitBreakerState
+	// Pulls of node skipped until this time while circuit breaker open
+	NextPullTime time.Time
+	openInterval time.Duration
+}
+
+// ResetResult is data of reset completed event
+type ResetResult struct {
+	Success bool
+	Error   string
+}
+
+// AddEventListener registers listener of observer events
+func (o *Observer) AddEventListener(listener EventListener) {
+	o.eventListeners = append(o.eventListeners, listener)
+}
+
+func (o *Observer) emitEvent(event Event) {
+	for _, listener := range o.eventListeners {
+		listener(event)
+	}
+}
+
+// GetNodesHealth returns results of last pull of every pulled node
+// Struct: {clusterName}.{groupName}.{nodeName} => NodeHealth
+func (o *Observer) GetNodesHealth() map[string]map[string]map[string]NodeHealth {
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	nodesHealth := map[string]map[string]map[string]NodeHealth{}
+	for nodeKey, health := range o.nodesHealth {
+		if _, ok := nodesHealth[nodeKey.clusterName]; !ok {
+			nodesHealth[nodeKey.clusterName] = map[string]map[string]NodeHealth{}
+		}
+
+		if _, ok := nodesHealth[nodeKey.clusterName][nodeKey.groupName]; !ok {
+			nodesHealth[nodeKey.clusterName][nodeKey.groupName] = map[string]NodeHealth{}
+		}
+
+		nodesHealth[nodeKey.clusterName][nodeKey.groupName][nodeKey.hostName] = health
+	}
+
+	return nodesHealth
+}
+
+// updateNodeHealth stores result of node pull and emits event when node became healthy or unhealthy
+func (o *Observer) updateNodeHealth(clusterName string, groupName string, hostName string, pullError error) {
+	now := time.Now()
+	key := nodeKey{clusterName, groupName, hostName}
+
+	o.statusesMutex.Lock()
+
+	if o.nodesHealth == nil {
+		o.nodesHealth = map[nodeKey]NodeHealth{}
+	}
+
+	previousHealth, wasPulled := o.nodesHealth[key]
+
+	health := NodeHealth{
+		Healthy:         pullError == nil,
+		LastPullTime:    now,
+		LastSuccessTime: previousHealth.LastSuccessTime,
+	}
+
+	if pullError != nil {
+		health.Error = pullError.Error()
+		health.ConsecutiveFailures = previousHealth.ConsecutiveFailures + 1
+	} else {
+		health.LastSuccessTime = now
+	}
+
+	o.applyCircuitBreaker(key, &health, previousHealth, now)
+
+	o.nodesHealth[key] = health
+
+	o.statusesMutex.Unlock()
+
+	for _, healthSender := range o.healthSenders {
+		healthSender.SendHealth(clusterName, groupName, hostName, health)
+	}
+
+	if !wasPulled || previousHealth.Healthy != health.Healthy || previousHealth.CircuitBreaker != health.CircuitBreaker {
+		o.emitEvent(Event{
+			Type:        EventNodeHealthChanged,
+			ClusterName: clusterName,
+			GroupName:   groupName,
+			HostName:    hostName,
+			Data:        health,
+		})
+	}
+}
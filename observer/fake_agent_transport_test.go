@@ -0,0 +1,130 @@
+package observer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+// fakeAgentTransport serves predefined in-memory responses instead of real agents,
+// so observer tested without nodes
+type fakeAgentTransport struct {
+	mutex     sync.Mutex
+	responses map[fakeAgentRequestKey]fakeAgentResponse
+	requests  []fakeAgentRequest
+}
+
+// fakeAgentRequest records request received by fake transport
+type fakeAgentRequest struct {
+	Host string
+	// Empty for status request
+	Command        string
+	IncludeScripts bool
+	Parameters     url.Values
+}
+
+type fakeAgentRequestKey struct {
+	host    string
+	command string
+}
+
+type fakeAgentResponse struct {
+	statusCode int
+	body       string
+	err        error
+}
+
+func newFakeAgentTransport() *fakeAgentTransport {
+	return &fakeAgentTransport{
+		responses: map[fakeAgentRequestKey]fakeAgentResponse{},
+	}
+}
+
+// SetStatusResponse defines response of agent on status request
+func (t *fakeAgentTransport) SetStatusResponse(host string, statusCode int, body string) {
+	t.setResponse(host, "", fakeAgentResponse{statusCode: statusCode, body: body})
+}
+
+// SetCommandResponse defines response of agent on command
+func (t *fakeAgentTransport) SetCommandResponse(host string, command string, statusCode int, body string) {
+	t.setResponse(host, command, fakeAgentResponse{statusCode: statusCode, body: body})
+}
+
+// SetStatusError makes status requests to node fail, e.g. as unreachable node
+func (t *fakeAgentTransport) SetStatusError(host string, err error) {
+	t.setResponse(host, "", fakeAgentResponse{err: err})
+}
+
+// SetCommandError makes command requests to node fail
+func (t *fakeAgentTransport) SetCommandError(host string, command string, err error) {
+	t.setResponse(host, command, fakeAgentResponse{err: err})
+}
+
+// GetRequests returns all requests received by transport in order of receiving
+func (t *fakeAgentTransport) GetRequests() []fakeAgentRequest {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]fakeAgentRequest{}, t.requests...)
+}
+
+func (t *fakeAgentTransport) FetchStatus(
+	groupConfig configuration.GroupConfig,
+	host string,
+	includeScripts bool,
+) (*http.Response, error) {
+	return t.respond(fakeAgentRequest{
+		Host:           host,
+		IncludeScripts: includeScripts,
+		Parameters:     buildStatusParameters(includeScripts),
+	})
+}
+
+func (t *fakeAgentTransport) ExecuteCommand(
+	groupConfig configuration.GroupConfig,
+	host string,
+	command string,
+	parameters url.Values,
+) (*http.Response, error) {
+	return t.respond(fakeAgentRequest{
+		Host:       host,
+		Command:    command,
+		Parameters: buildCommandParameters(command, parameters),
+	})
+}
+
+func (t *fakeAgentTransport) setResponse(host string, command string, response fakeAgentResponse) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.responses[fakeAgentRequestKey{host: host, command: command}] = response
+}
+
+func (t *fakeAgentTransport) respond(request fakeAgentRequest) (*http.Response, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.requests = append(t.requests, request)
+
+	response, ok := t.responses[fakeAgentRequestKey{host: request.Host, command: request.Command}]
+	if !ok {
+		return nil, fmt.Errorf("No fake response defined for command '%s' of node %s", request.Command, request.Host)
+	}
+
+	if response.err != nil {
+		return nil, response.err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.statusCode, http.StatusText(response.statusCode)),
+		StatusCode:    response.statusCode,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(response.body)),
+		ContentLength: int64(len(response.body)),
+	}, nil
+}
@@ -0,0 +1,302 @@
+package observer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://fastcgi-archives.github.io/FastCGI_Specification.html
+const (
+	fastCGIVersion = 1
+
+	fastCGIBeginRequest = 1
+	fastCGIEndRequest   = 3
+	fastCGIParams       = 4
+	fastCGIStdin        = 5
+	fastCGIStdout       = 6
+	fastCGIStderr       = 7
+
+	fastCGIResponder = 1
+
+	// protocol status of end request record
+	fastCGIRequestComplete = 0
+	fastCGICantMpxConn     = 1
+	fastCGIOverloaded      = 2
+	fastCGIUnknownRole     = 3
+
+	// single request sent over connection
+	fastCGIRequestID = 1
+
+	fastCGIMaxContentLength = 65535
+)
+
+// Prefix of FastCGI address which points to unix socket instead of TCP address
+const fastCGIUnixSocketPrefix = "unix:"
+
+// Maximal size of stderr output kept for error message, script may write warnings on every request
+const fastCGIMaxStderrSize = 64 * 1024
+
+type fastCGIRecordHeader struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+// FastCGIRequest describes execution of script by PHP-FPM
+type FastCGIRequest struct {
+	// TCP address "host:port" or unix socket "unix:/path/to/socket"
+	Address    string
+	ScriptPath string
+	// Encoded query string passed to script
+	Query string
+}
+
+// DoFastCGIRequest executes script on PHP-FPM over FastCGI as GET request and returns its response.
+// Response body is streamed from connection, so it must be closed by caller.
+func DoFastCGIRequest(request FastCGIRequest, timeout time.Duration) (*http.Response, error) {
+	network, address := "tcp", request.Address
+	if strings.HasPrefix(address, fastCGIUnixSocketPrefix) {
+		network, address = "unix", strings.TrimPrefix(address, fastCGIUnixSocketPrefix)
+	}
+
+	connection, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	connection.SetDeadline(time.Now().Add(timeout))
+
+	if err := writeFastCGIRequest(connection, request); err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	// stdout of script streamed through pipe while records read from connection
+	stdoutReader, stdoutWriter := io.Pipe()
+	go readFastCGIRecords(connection, stdoutWriter)
+
+	response, err := readCGIResponse(stdoutReader)
+	if err != nil {
+		stdoutReader.Close()
+		connection.Close()
+		return nil, err
+	}
+
+	response.Body = &fastCGIResponseBody{
+		Reader:     response.Body,
+		pipe:       stdoutReader,
+		connection: connection,
+	}
+
+	return response, nil
+}
+
+type fastCGIResponseBody struct {
+	io.Reader
+	pipe       *io.PipeReader
+	connection net.Conn
+}
+
+func (b *fastCGIResponseBody) Close() error {
+	b.pipe.Close()
+
+	return b.connection.Close()
+}
+
+func writeFastCGIRequest(writer io.Writer, request FastCGIRequest) error {
+	bufferedWriter := bufio.NewWriter(writer)
+
+	// role and flags, connection closed after request
+	beginRequestBody := []byte{0, fastCGIResponder, 0, 0, 0, 0, 0, 0}
+	if err := writeFastCGIRecord(bufferedWriter, fastCGIBeginRequest, beginRequestBody); err != nil {
+		return err
+	}
+
+	queryString := request.Query
+
+	params := map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"SERVER_SOFTWARE":   "opcache-dashboard",
+		"SERVER_PROTOCOL":   "HTTP/1.1",
+		"REQUEST_METHOD":    http.MethodGet,
+		"SCRIPT_FILENAME":   request.ScriptPath,
+		"SCRIPT_NAME":       "/" + filepath.Base(request.ScriptPath),
+		"DOCUMENT_ROOT":     filepath.Dir(request.ScriptPath),
+		"REQUEST_URI":       "/" + filepath.Base(request.ScriptPath) + "?" + queryString,
+		"QUERY_STRING":      queryString,
+		"CONTENT_LENGTH":    "0",
+		"REMOTE_ADDR":       "127.0.0.1",
+	}
+
+	paramsBody := bytes.Buffer{}
+	for name, value := range params {
+		writeFastCGINameValueLength(&paramsBody, len(name))
+		writeFastCGINameValueLength(&paramsBody, len(value))
+		paramsBody.WriteString(name)
+		paramsBody.WriteString(value)
+	}
+
+	for paramsBody.Len() > 0 {
+		if err := writeFastCGIRecord(bufferedWriter, fastCGIParams, paramsBody.Next(fastCGIMaxContentLength)); err != nil {
+			return err
+		}
+	}
+
+	// empty records close params and stdin streams
+	if err := writeFastCGIRecord(bufferedWriter, fastCGIParams, nil); err != nil {
+		return err
+	}
+
+	if err := writeFastCGIRecord(bufferedWriter, fastCGIStdin, nil); err != nil {
+		return err
+	}
+
+	return bufferedWriter.Flush()
+}
+
+func writeFastCGIRecord(writer io.Writer, recordType uint8, content []byte) error {
+	header := fastCGIRecordHeader{
+		Version:       fastCGIVersion,
+		Type:          recordType,
+		RequestID:     fastCGIRequestID,
+		ContentLength: uint16(len(content)),
+		PaddingLength: uint8(-len(content) & 7),
+	}
+
+	if err := binary.Write(writer, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(content); err != nil {
+		return err
+	}
+
+	_, err := writer.Write(make([]byte, header.PaddingLength))
+
+	return err
+}
+
+func writeFastCGINameValueLength(buffer *bytes.Buffer, length int) {
+	if length <= 127 {
+		buffer.WriteByte(byte(length))
+		return
+	}
+
+	binary.Write(buffer, binary.BigEndian, uint32(length)|1<<31)
+}
+
+// readFastCGIRecords copies stdout of script to writer until end of request
+func readFastCGIRecords(reader io.Reader, stdoutWriter *io.PipeWriter) {
+	bufferedReader := bufio.NewReader(reader)
+	stderr := bytes.Buffer{}
+	hasStdout := false
+
+	for {
+		header := fastCGIRecordHeader{}
+		if err := binary.Read(bufferedReader, binary.BigEndian, &header); err != nil {
+			stdoutWriter.CloseWithError(fmt.Errorf("Can not read FastCGI response: %w", err))
+			return
+		}
+
+		content := io.LimitReader(bufferedReader, int64(header.ContentLength))
+
+		switch header.Type {
+		case fastCGIStdout:
+			hasStdout = hasStdout || header.ContentLength > 0
+			if _, err := io.Copy(stdoutWriter, content); err != nil {
+				// body closed by reader
+				return
+			}
+		case fastCGIStderr:
+			io.Copy(&stderr, io.LimitReader(content, int64(fastCGIMaxStderrSize-stderr.Len())))
+			io.Copy(io.Discard, content)
+		case fastCGIEndRequest:
+			endRequestBody := make([]byte, header.ContentLength)
+			if _, err := io.ReadFull(content, endRequestBody); err != nil || len(endRequestBody) < 8 {
+				stdoutWriter.CloseWithError(errors.New("Can not read FastCGI response: invalid end of request"))
+				return
+			}
+
+			// request rejected by PHP-FPM
+			if protocolStatus := endRequestBody[4]; protocolStatus != fastCGIRequestComplete {
+				stdoutWriter.CloseWithError(fmt.Errorf("FastCGI request rejected: %s", fastCGIProtocolStatusText(protocolStatus)))
+				return
+			}
+
+			// script not executed at all, e.g. "Primary script unknown"
+			if !hasStdout && stderr.Len() > 0 {
+				stdoutWriter.CloseWithError(fmt.Errorf("FastCGI error: %s", strings.TrimSpace(stderr.String())))
+				return
+			}
+
+			stdoutWriter.Close()
+			return
+		default:
+			io.Copy(io.Discard, content)
+		}
+
+		if _, err := bufferedReader.Discard(int(header.PaddingLength)); err != nil {
+			stdoutWriter.CloseWithError(err)
+			return
+		}
+	}
+}
+
+func fastCGIProtocolStatusText(protocolStatus uint8) string {
+	switch protocolStatus {
+	case fastCGICantMpxConn:
+		return "can not multiplex connection"
+	case fastCGIOverloaded:
+		return "overloaded"
+	case fastCGIUnknownRole:
+		return "unknown role"
+	default:
+		return fmt.Sprintf("protocol status %d", protocolStatus)
+	}
+}
+
+// readCGIResponse reads CGI headers of script output, "Status" header defines response status
+func readCGIResponse(reader io.Reader) (*http.Response, error) {
+	bufferedReader := bufio.NewReader(reader)
+
+	mimeHeader, err := textproto.NewReader(bufferedReader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("Can not read FastCGI response headers: %w", err)
+	}
+
+	header := http.Header(mimeHeader)
+
+	response := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bufferedReader),
+		ContentLength: -1,
+	}
+
+	if status := header.Get("Status"); status != "" {
+		statusCode, err := strconv.Atoi(strings.SplitN(status, " ", 2)[0])
+		if err != nil {
+			return nil, errors.New("Invalid status of FastCGI response " + status)
+		}
+
+		response.Status = status
+		response.StatusCode = statusCode
+	}
+
+	return response, nil
+}
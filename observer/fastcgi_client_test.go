@@ -0,0 +1,263 @@
+package observer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeFastCGIRecord struct {
+	recordType uint8
+	content    string
+}
+
+// startFakeFastCGIResponder accepts single connection, reads request and replies with passed records.
+// Connection closed after records written, so records without end of request emulate early close.
+func startFakeFastCGIResponder(t *testing.T, records []fakeFastCGIRecord) (string, <-chan map[string]string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	receivedParams := make(chan map[string]string, 1)
+
+	go func() {
+		connection, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer connection.Close()
+
+		params, err := readFakeFastCGIRequest(bufio.NewReader(connection))
+		if err != nil {
+			t.Errorf("can not read request: %v", err)
+			return
+		}
+
+		receivedParams <- params
+
+		writer := bufio.NewWriter(connection)
+		for _, record := range records {
+			writeFastCGIRecord(writer, record.recordType, []byte(record.content))
+		}
+
+		writer.Flush()
+	}()
+
+	return listener.Addr().String(), receivedParams
+}
+
+// readFakeFastCGIRequest reads records until end of stdin stream and decodes params
+func readFakeFastCGIRequest(reader *bufio.Reader) (map[string]string, error) {
+	paramsBody := []byte{}
+
+	for {
+		header := fastCGIRecordHeader{}
+		if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+			return nil, err
+		}
+
+		content := make([]byte, int(header.ContentLength)+int(header.PaddingLength))
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return nil, err
+		}
+
+		switch header.Type {
+		case fastCGIParams:
+			paramsBody = append(paramsBody, content[:header.ContentLength]...)
+		case fastCGIStdin:
+			if header.ContentLength == 0 {
+				return decodeFakeFastCGIParams(paramsBody), nil
+			}
+		}
+	}
+}
+
+func decodeFakeFastCGIParams(paramsBody []byte) map[string]string {
+	params := map[string]string{}
+
+	readLength := func() int {
+		if paramsBody[0]>>7 == 0 {
+			length := int(paramsBody[0])
+			paramsBody = paramsBody[1:]
+			return length
+		}
+
+		length := int(binary.BigEndian.Uint32(paramsBody) &^ (1 << 31))
+		paramsBody = paramsBody[4:]
+		return length
+	}
+
+	for len(paramsBody) > 0 {
+		nameLength := readLength()
+		valueLength := readLength()
+		params[string(paramsBody[:nameLength])] = string(paramsBody[nameLength : nameLength+valueLength])
+		paramsBody = paramsBody[nameLength+valueLength:]
+	}
+
+	return params
+}
+
+func fakeFastCGIEndRequest(protocolStatus uint8) fakeFastCGIRecord {
+	return fakeFastCGIRecord{
+		recordType: fastCGIEndRequest,
+		content:    string([]byte{0, 0, 0, 0, protocolStatus, 0, 0, 0}),
+	}
+}
+
+func TestDoFastCGIRequest(t *testing.T) {
+	longLine := strings.Repeat("x", fastCGIMaxContentLength)
+
+	testCases := []struct {
+		name       string
+		records    []fakeFastCGIRecord
+		statusCode int
+		body       string
+		err        string
+	}{
+		{
+			name: "single stdout record",
+			records: []fakeFastCGIRecord{
+				{fastCGIStdout, "Content-Type: application/json\r\n\r\n{\"status\":false}"},
+				{fastCGIStdout, ""},
+				fakeFastCGIEndRequest(fastCGIRequestComplete),
+			},
+			statusCode: 200,
+			body:       `{"status":false}`,
+		},
+		{
+			name: "body split over records with stderr between them",
+			records: []fakeFastCGIRecord{
+				{fastCGIStdout, "Status: 403 Forbidden\r\nContent-Type: text/plain\r\n\r\n"},
+				{fastCGIStdout, longLine},
+				{fastCGIStderr, "PHP Warning:  Undefined variable $x"},
+				{fastCGIStdout, "tail"},
+				{fastCGIStdout, ""},
+				fakeFastCGIEndRequest(fastCGIRequestComplete),
+			},
+			statusCode: 403,
+			body:       longLine + "tail",
+		},
+		{
+			name: "script not found",
+			records: []fakeFastCGIRecord{
+				{fastCGIStderr, "Primary script unknown\n"},
+				{fastCGIStdout, ""},
+				fakeFastCGIEndRequest(fastCGIRequestComplete),
+			},
+			err: "FastCGI error: Primary script unknown",
+		},
+		{
+			name:    "overloaded",
+			records: []fakeFastCGIRecord{fakeFastCGIEndRequest(fastCGIOverloaded)},
+			err:     "FastCGI request rejected: overloaded",
+		},
+		{
+			name:    "unknown role",
+			records: []fakeFastCGIRecord{fakeFastCGIEndRequest(fastCGIUnknownRole)},
+			err:     "FastCGI request rejected: unknown role",
+		},
+		{
+			name:    "can not multiplex connection",
+			records: []fakeFastCGIRecord{fakeFastCGIEndRequest(fastCGICantMpxConn)},
+			err:     "FastCGI request rejected: can not multiplex connection",
+		},
+		{
+			name: "rejected after headers",
+			records: []fakeFastCGIRecord{
+				{fastCGIStdout, "Content-Type: application/json\r\n\r\n{"},
+				fakeFastCGIEndRequest(fastCGIOverloaded),
+			},
+			err: "FastCGI request rejected: overloaded",
+		},
+		{
+			name:    "closed before response",
+			records: []fakeFastCGIRecord{},
+			err:     "Can not read FastCGI response",
+		},
+		{
+			name: "closed before end of request",
+			records: []fakeFastCGIRecord{
+				{fastCGIStdout, "Content-Type: application/json\r\n\r\n{\"status\":"},
+			},
+			err: "Can not read FastCGI response",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			address, receivedParams := startFakeFastCGIResponder(t, testCase.records)
+
+			response, err := DoFastCGIRequest(
+				FastCGIRequest{Address: address, ScriptPath: "/var/www/agent.php", Query: "command=status&scripts=1"},
+				5*time.Second,
+			)
+
+			body := ""
+			if err == nil {
+				var bodyBytes []byte
+				bodyBytes, err = io.ReadAll(response.Body)
+				response.Body.Close()
+				body = string(bodyBytes)
+			}
+
+			params := <-receivedParams
+			if params["SCRIPT_FILENAME"] != "/var/www/agent.php" || params["QUERY_STRING"] != "command=status&scripts=1" {
+				t.Errorf("unexpected params: %v", params)
+			}
+
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("error: got %v, want %q", err, testCase.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if response.StatusCode != testCase.statusCode {
+				t.Errorf("status: got %d, want %d", response.StatusCode, testCase.statusCode)
+			}
+
+			if body != testCase.body {
+				t.Errorf("body: got %d bytes, want %d bytes", len(body), len(testCase.body))
+			}
+		})
+	}
+}
+
+func TestDoFastCGIRequestStderrLimit(t *testing.T) {
+	// stderr beyond limit discarded, while records after it still read
+	address, receivedParams := startFakeFastCGIResponder(t, []fakeFastCGIRecord{
+		{fastCGIStderr, strings.Repeat("e", fastCGIMaxContentLength)},
+		{fastCGIStderr, strings.Repeat("e", fastCGIMaxContentLength)},
+		{fastCGIStderr, "tail"},
+		{fastCGIStdout, ""},
+		fakeFastCGIEndRequest(fastCGIRequestComplete),
+	})
+
+	response, err := DoFastCGIRequest(FastCGIRequest{Address: address, ScriptPath: "/var/www/missing.php"}, 5*time.Second)
+	if err == nil {
+		_, err = io.ReadAll(response.Body)
+		response.Body.Close()
+	}
+
+	<-receivedParams
+
+	if expected := "FastCGI error: " + strings.Repeat("e", fastCGIMaxStderrSize); err == nil || !strings.HasSuffix(err.Error(), expected) {
+		t.Fatalf("expected error with %d bytes of stderr, got %d bytes", fastCGIMaxStderrSize, len(fmt.Sprint(err)))
+	}
+}
@@ -0,0 +1,152 @@
+package observer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+// ClustersFpmStatuses represents collection of PHP-FPM pool statuses of groups with configured status page
+// Struct: {clusterName}.{groupName}.{nodeName} => NodeFpmStatus
+type ClustersFpmStatuses map[string]map[string]map[string]NodeFpmStatus
+
+// NodeFpmStatus represents status page of PHP-FPM pool on single node
+type NodeFpmStatus struct {
+	Pool                string // pool
+	ProcessManager      string // process manager
+	StartTime           int64  // start time
+	StartSince          int64  // start since
+	AcceptedConnections int    // accepted conn
+	// Requests waiting for free process
+	ListenQueue        int // listen queue
+	MaxListenQueue     int // max listen queue
+	ListenQueueLength  int // listen queue len
+	IdleProcesses      int // idle processes
+	ActiveProcesses    int // active processes
+	TotalProcesses     int // total processes
+	MaxActiveProcesses int // max active processes
+	// Count of times pm.max_children reached, so requests waited for free process
+	MaxChildrenReached int // max children reached
+	SlowRequests       int // slow requests
+}
+
+type fpmStatusMessage struct {
+	Pool                string `json:"pool"`
+	ProcessManager      string `json:"process manager"`
+	StartTime           int64  `json:"start time"`
+	StartSince          int64  `json:"start since"`
+	AcceptedConnections int    `json:"accepted conn"`
+	ListenQueue         int    `json:"listen queue"`
+	MaxListenQueue      int    `json:"max listen queue"`
+	ListenQueueLength   int    `json:"listen queue len"`
+	IdleProcesses       int    `json:"idle processes"`
+	ActiveProcesses     int    `json:"active processes"`
+	TotalProcesses      int    `json:"total processes"`
+	MaxActiveProcesses  int    `json:"max active processes"`
+	MaxChildrenReached  int    `json:"max children reached"`
+	SlowRequests        int    `json:"slow requests"`
+}
+
+// ParseFpmStatus parses PHP-FPM status page in JSON format
+func ParseFpmStatus(reader io.Reader) (*NodeFpmStatus, error) {
+	message := fpmStatusMessage{}
+
+	if err := json.NewDecoder(reader).Decode(&message); err != nil {
+		return nil, &ParseError{Err: err}
+	}
+
+	fpmStatus := NodeFpmStatus(message)
+
+	return &fpmStatus, nil
+}
+
+// GetFpmStatistics returns PHP-FPM pool statuses of nodes in groups with configured status page
+func (o *Observer) GetFpmStatistics() ClustersFpmStatuses {
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	statuses := ClustersFpmStatuses{}
+	for clusterName, groups := range o.fpmStatuses {
+		statuses[clusterName] = map[string]map[string]NodeFpmStatus{}
+		for groupName, hosts := range groups {
+			statuses[clusterName][groupName] = map[string]NodeFpmStatus{}
+			for hostName, status := range hosts {
+				statuses[clusterName][groupName][hostName] = status
+			}
+		}
+	}
+
+	return statuses
+}
+
+// fetchFpmStatus pulls status page of PHP-FPM pool on node
+func (o *Observer) fetchFpmStatus(groupConfig configuration.GroupConfig, host string) (*NodeFpmStatus, error) {
+	fpmStatusURL, err := buildFpmStatusURL(buildPullAgentUrl(groupConfig.FpmStatusUrlPattern, host))
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("GET", fpmStatusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupConfig.BasicAuthCredentials != nil {
+		request.SetBasicAuth(groupConfig.BasicAuthCredentials.User, groupConfig.BasicAuthCredentials.Password)
+	}
+
+	response, err := o.getHTTPClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeResponseBody(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("FPM status page of node %s return error %s", host, response.Status)
+	}
+
+	fpmStatus, err := ParseFpmStatus(newLimitedReader(response.Body, o.getMaxResponseSize()))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid FPM status of node %s: %w", host, err)
+	}
+
+	return fpmStatus, nil
+}
+
+// buildFpmStatusURL requests JSON format of status page if not requested in configured pattern
+func buildFpmStatusURL(statusURL string) (string, error) {
+	parsedURL, err := url.Parse(statusURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsedURL.Query()
+	if _, ok := query["json"]; !ok {
+		query.Set("json", "")
+		parsedURL.RawQuery = query.Encode()
+	}
+
+	return parsedURL.String(), nil
+}
+
+// pullFpmStatus adds status of PHP-FPM pool to node statistics if status page configured for group.
+// Unavailable status page does not fail pull of node.
+func (o *Observer) pullFpmStatus(groupConfig configuration.GroupConfig, host string, nodeStatistics *NodeStatistics) {
+	if groupConfig.FpmStatusUrlPattern == "" {
+		return
+	}
+
+	fpmStatus, err := o.fetchFpmStatus(groupConfig, host)
+	if err != nil {
+		log.Println(fmt.Sprintf("%v", err))
+		return
+	}
+
+	nodeStatistics.FpmStatistics = fpmStatus
+}
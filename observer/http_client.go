@@ -0,0 +1,73 @@
+package observer
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxResponseSizeBytes limits size of agent response when no limit configured
+const DefaultMaxResponseSizeBytes = 256 * 1024 * 1024
+
+// Timeout of single request to agent
+const agentRequestTimeout = 60 * time.Second
+
+// Size of response body drained before closing to reuse connection
+const maxDrainedBodyBytes = 64 * 1024
+
+// ErrResponseTooLarge returned when agent response exceeds configured limit
+var ErrResponseTooLarge = errors.New("Agent response exceeds maximum allowed size")
+
+// newAgentHTTPClient builds client shared by all requests to agents, so connections are reused
+func newAgentHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 4
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   agentRequestTimeout,
+	}
+}
+
+// limitedReader fails with ErrResponseTooLarge instead of silently truncating stream
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func newLimitedReader(reader io.Reader, limit int64) io.Reader {
+	return &limitedReader{
+		reader:    reader,
+		remaining: limit,
+	}
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// limit reached, check whether stream has more data
+		var probe [1]byte
+		n, err := r.reader.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+
+	return n, err
+}
+
+// closeResponseBody drains rest of small body so connection may be reused, and closes it
+func closeResponseBody(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainedBodyBytes))
+	body.Close()
+}
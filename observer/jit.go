@@ -0,0 +1,126 @@
+package observer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JitStatus represents state of OPcache JIT, reported since PHP 8
+type JitStatus struct {
+	Enabled    bool // status.jit.enabled
+	On         bool // status.jit.on
+	Kind       int  // status.jit.kind
+	OptLevel   int  // status.jit.opt_level
+	OptFlags   int  // status.jit.opt_flags
+	BufferSize int  // status.jit.buffer_size
+	BufferFree int  // status.jit.buffer_free
+	BufferUsed int  // may be defined as BufferSize-BufferFree
+	// Decoded configuration.directives.opcache.jit, nil if directive has unknown format
+	Mode *JitMode
+}
+
+// JitMode describes opcache.jit directive, which is alias or CRTO digits
+type JitMode struct {
+	Directive          string
+	Disabled           bool
+	CPUOptimization    string // C digit
+	RegisterAllocation string // R digit
+	Trigger            string // T digit
+	OptimizationLevel  string // O digit
+}
+
+// https://www.php.net/manual/en/opcache.configuration.php#ini.opcache.jit
+var jitModeAliases = map[string]string{
+	"on":       "1254",
+	"tracing":  "1254",
+	"function": "1205",
+}
+
+var jitCPUOptimizations = []string{
+	"Disable CPU-specific optimization",
+	"Enable use of AVX, if the CPU supports it",
+}
+
+var jitRegisterAllocations = []string{
+	"Don't perform register allocation",
+	"Perform block-local register allocation",
+	"Perform global register allocation",
+}
+
+var jitTriggers = []string{
+	"Compile all functions on script load",
+	"Compile functions on first execution",
+	"Profile functions on first request and compile the hottest functions afterwards",
+	"Profile on the fly and compile hot functions",
+	"Currently unused",
+	"Use tracing JIT. Profile on the fly and compile traces for hot code segments",
+}
+
+var jitOptimizationLevels = []string{
+	"No JIT",
+	"Minimal JIT (call standard VM handlers)",
+	"Inline VM handlers",
+	"Use type inference",
+	"Use call graph",
+	"Optimize whole script",
+}
+
+// DecodeJitMode decodes value of opcache.jit directive to human-readable mode
+func DecodeJitMode(directive string) (*JitMode, error) {
+	normalizedDirective := strings.ToLower(strings.TrimSpace(directive))
+
+	switch normalizedDirective {
+	case "", "0", "off", "disable":
+		return &JitMode{Directive: directive, Disabled: true}, nil
+	}
+
+	if alias, ok := jitModeAliases[normalizedDirective]; ok {
+		normalizedDirective = alias
+	}
+
+	if len(normalizedDirective) != 4 {
+		return nil, fmt.Errorf("Unknown opcache.jit value %s", directive)
+	}
+
+	digits := make([]int, 4)
+	for position, digit := range normalizedDirective {
+		value, err := strconv.Atoi(string(digit))
+		if err != nil {
+			return nil, fmt.Errorf("Unknown opcache.jit value %s", directive)
+		}
+
+		digits[position] = value
+	}
+
+	describeDigit := func(descriptions []string, digit int) (string, error) {
+		if digit >= len(descriptions) {
+			return "", fmt.Errorf("Unknown opcache.jit value %s", directive)
+		}
+
+		return descriptions[digit], nil
+	}
+
+	jitMode := JitMode{Directive: directive}
+
+	var err error
+	if jitMode.CPUOptimization, err = describeDigit(jitCPUOptimizations, digits[0]); err != nil {
+		return nil, err
+	}
+
+	if jitMode.RegisterAllocation, err = describeDigit(jitRegisterAllocations, digits[1]); err != nil {
+		return nil, err
+	}
+
+	if jitMode.Trigger, err = describeDigit(jitTriggers, digits[2]); err != nil {
+		return nil, err
+	}
+
+	if jitMode.OptimizationLevel, err = describeDigit(jitOptimizationLevels, digits[3]); err != nil {
+		return nil, err
+	}
+
+	jitMode.Disabled = digits[3] == 0
+
+	return &jitMode, nil
+}
@@ -8,3 +8,13 @@ type MetricSenderInterface interface {
 		nodeStatistics NodeStatistics,
 	)
 }
+
+// NodeHealthSenderInterface receives result of every pull of node, including failed ones
+type NodeHealthSenderInterface interface {
+	SendHealth(
+		clusterName string,
+		groupName string,
+		hostName string,
+		health NodeHealth,
+	)
+}
@@ -2,10 +2,10 @@ package observer
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"strings"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/GoMetric/opcache-dashboard/configuration"
@@ -14,17 +14,53 @@ import (
 // Observer periodically reads status of observable nodes and aggregates received data
 type Observer struct {
 	metricSenders    []MetricSenderInterface
-	agentPullTicker  *time.Ticker
+	healthSenders    []NodeHealthSenderInterface
+	pullListeners    []PullCompleteListener
+	eventListeners   []EventListener
+	listenersMutex   sync.Mutex
+	stopPulling      chan struct{}
 	opcacheStatuses  ClustersOpcacheStatuses
 	apcuStatuses     ClustersApcuStatuses
+	fpmStatuses      ClustersFpmStatuses
 	parser           AgentMessageParser
+	statusesMutex    sync.RWMutex
+	pullMutex        sync.Mutex
+	pullInProgress   bool
+	rateSamples      map[nodeKey]rateSample
+	httpClient       *http.Client
+	httpClientOnce   sync.Once
+	agentTransport   AgentTransportInterface
+	transportOnce    sync.Once
+	nodesHealth      map[nodeKey]NodeHealth
+	agentsInfo       map[nodeKey]AgentInfo
 	Clusters         map[string]configuration.ClusterConfig
-	LastStatusUpdate time.Time
+	lastStatusUpdate time.Time
+	// Interval of groups without own interval, set when pulling started
+	defaultPullInterval time.Duration
+	// Responses of agents larger than limit rejected, default limit used when zero
+	MaxResponseSizeBytes int64
+	// Retries of failed pulls disabled when zero
+	Retry configuration.RetryConfig
+	// Circuit breaker of nodes disabled when zero
+	CircuitBreaker configuration.CircuitBreakerConfig
 }
 
+// nodeKey identifies node in internal collections
+type nodeKey struct {
+	clusterName string
+	groupName   string
+	hostName    string
+}
+
+// PullCompleteListener called after all agents pulled or after every pull cycle of group
+type PullCompleteListener func()
+
 type NodeStatistics struct {
 	OpcacheStatistics NodeOpcacheStatus
 	ApcuStatistics    NodeApcuStatus
+	// Status of PHP-FPM pool, nil when status page not configured for group or not available
+	FpmStatistics *NodeFpmStatus
+	Agent         AgentInfo
 }
 
 func NewObserver(clusters map[string]configuration.ClusterConfig) *Observer {
@@ -36,17 +72,43 @@ func NewObserver(clusters map[string]configuration.ClusterConfig) *Observer {
 	return &observer
 }
 
+// SetAgentTransport replaces default transport of requests to agents, must be called before pulling
+func (o *Observer) SetAgentTransport(agentTransport AgentTransportInterface) {
+	o.agentTransport = agentTransport
+}
+
 func (o *Observer) AddMetricSender(metricSender MetricSenderInterface) {
 	o.metricSenders = append(o.metricSenders, metricSender)
 }
 
+// AddNodeHealthSender registers sender of node health, called after every pull of node
+func (o *Observer) AddNodeHealthSender(healthSender NodeHealthSenderInterface) {
+	o.healthSenders = append(o.healthSenders, healthSender)
+}
+
+// AddPullCompleteListener registers listener called after every pull of all agents or of group
+func (o *Observer) AddPullCompleteListener(listener PullCompleteListener) {
+	o.pullListeners = append(o.pullListeners, listener)
+}
+
 // StartPulling observing of configured nodes
 func (o *Observer) StartPulling(
 	refreshIntervalNanoSeconds int64,
 ) {
-	// init statuses structure
+	o.initStatuses()
+
+	// start observing nodes of every group on own schedule
+	o.stopPulling = make(chan struct{})
+	o.defaultPullInterval = time.Duration(refreshIntervalNanoSeconds)
+
+	go o.pullAgentsOnSchedule(o.defaultPullInterval)
+}
+
+// initStatuses creates empty statuses of all configured nodes
+func (o *Observer) initStatuses() {
 	o.opcacheStatuses = ClustersOpcacheStatuses{}
 	o.apcuStatuses = ClustersApcuStatuses{}
+	o.fpmStatuses = ClustersFpmStatuses{}
 
 	for clusterName, clusterConfig := range o.Clusters {
 		o.opcacheStatuses[clusterName] = map[string]map[string]NodeOpcacheStatus{}
@@ -60,44 +122,109 @@ func (o *Observer) StartPulling(
 				o.opcacheStatuses[clusterName][groupName][host] = NodeOpcacheStatus{}
 				o.apcuStatuses[clusterName][groupName][host] = NodeApcuStatus{}
 			}
-		}
-	}
 
-	// start ticker
-	o.agentPullTicker = time.NewTicker(time.Duration(refreshIntervalNanoSeconds))
+			if groupConfig.FpmStatusUrlPattern != "" {
+				if _, ok := o.fpmStatuses[clusterName]; !ok {
+					o.fpmStatuses[clusterName] = map[string]map[string]NodeFpmStatus{}
+				}
 
-	// start observing nodes on tick
-	go o.pullAgentsOnTick()
+				o.fpmStatuses[clusterName][groupName] = map[string]NodeFpmStatus{}
+			}
+		}
+	}
 }
 
-// StopPulling stops observing ticker
+// StopPulling stops observing schedules
 func (o *Observer) StopPulling() {
-	o.agentPullTicker.Stop()
+	close(o.stopPulling)
 }
 
 // GetOpcacheStatistics returns pulled opcache statuses for all clusters
 func (o *Observer) GetOpcacheStatistics() ClustersOpcacheStatuses {
-	return o.opcacheStatuses
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	statuses := ClustersOpcacheStatuses{}
+	for clusterName, groups := range o.opcacheStatuses {
+		statuses[clusterName] = map[string]map[string]NodeOpcacheStatus{}
+		for groupName, hosts := range groups {
+			statuses[clusterName][groupName] = map[string]NodeOpcacheStatus{}
+			for hostName, status := range hosts {
+				statuses[clusterName][groupName][hostName] = status
+			}
+		}
+	}
+
+	return statuses
+}
+
+// GetLastStatusUpdate returns time of last successful pull of any node
+func (o *Observer) GetLastStatusUpdate() time.Time {
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	return o.lastStatusUpdate
 }
 
 // GetApcuStatistics returns pulled APCu statuses for all clusters
 func (o *Observer) GetApcuStatistics() ClustersApcuStatuses {
-	return o.apcuStatuses
+	o.statusesMutex.RLock()
+	defer o.statusesMutex.RUnlock()
+
+	statuses := ClustersApcuStatuses{}
+	for clusterName, groups := range o.apcuStatuses {
+		statuses[clusterName] = map[string]map[string]NodeApcuStatus{}
+		for groupName, hosts := range groups {
+			statuses[clusterName][groupName] = map[string]NodeApcuStatus{}
+			for hostName, status := range hosts {
+				statuses[clusterName][groupName][hostName] = status
+			}
+		}
+	}
+
+	return statuses
 }
 
+// ResetOpcache resets opcache on node and pulls its fresh status
 func (o *Observer) ResetOpcache(clusterName string, groupName string, hostName string) error {
+	err := o.resetOpcache(clusterName, groupName, hostName)
+
+	resetResult := ResetResult{
+		Success: err == nil,
+	}
+
+	if err != nil {
+		resetResult.Error = err.Error()
+	}
+
+	o.emitEvent(Event{
+		Type:        EventResetCompleted,
+		ClusterName: clusterName,
+		GroupName:   groupName,
+		HostName:    hostName,
+		Data:        resetResult,
+	})
+
+	return err
+}
+
+func (o *Observer) resetOpcache(clusterName string, groupName string, hostName string) error {
 	groupConfig := o.Clusters[clusterName].Groups[groupName]
 
-	pullAgentURL := o.buildPullAgentUrl(groupConfig.UrlPattern, hostName) + "?command=reset"
+	if !o.getAgentInfo(clusterName, groupName, hostName).SupportsCommand(AgentCommandReset) {
+		return ErrCommandNotSupported
+	}
 
-	log.Printf(fmt.Sprintf("Reseting node opcache %s", pullAgentURL))
+	log.Printf("Reseting node opcache %s", hostName)
 
-	response, error := http.Get(pullAgentURL)
+	response, error := o.getAgentTransport().ExecuteCommand(groupConfig, hostName, AgentCommandReset, nil)
 
 	if error != nil {
 		return error
 	}
 
+	closeResponseBody(response.Body)
+
 	if response.StatusCode != http.StatusOK {
 		return fmt.Errorf("Observable node return error %s", response.Status)
 	}
@@ -107,31 +234,144 @@ func (o *Observer) ResetOpcache(clusterName string, groupName string, hostName s
 		clusterName,
 		groupName,
 		hostName,
+		true,
+		o.unscheduledPullRetryDeadline(clusterName, groupName),
 	)
 
 	return nil
 }
 
-func (o *Observer) pullAgentsOnTick() {
-	for ; true; <-o.agentPullTicker.C {
-		o.PullAgents()
+// InvalidateScript invalidates single script in OPcache of node, statistics of node refreshed in background
+func (o *Observer) InvalidateScript(clusterName string, groupName string, hostName string, scriptPath string) error {
+	err := o.sendAgentCommand(
+		clusterName,
+		groupName,
+		hostName,
+		AgentCommandInvalidate,
+		url.Values{"script": {scriptPath}},
+		nil,
+	)
+
+	if err != nil {
+		return err
 	}
+
+	go o.pullNodes(o.Clusters[clusterName].Groups[groupName], clusterName, groupName, []string{hostName})
+
+	return nil
 }
 
-// PullAgents fetches data from all agents and store it to internal struct
+func (o *Observer) pullAgentsOnSchedule(defaultInterval time.Duration) {
+	// first pull of all agents fetches script lists, following ones
+	// fetch them only for groups without own scripts schedule
+	o.PullAgents()
+
+	for clusterName, clusterConfig := range o.Clusters {
+		for groupName, groupConfig := range clusterConfig.Groups {
+			// script lists of groups with own scripts schedule pulled separately
+			hasScriptsSchedule := groupConfig.ScriptsPullIntervalSeconds > 0
+
+			go o.pullGroupOnSchedule(
+				o.stopPulling,
+				clusterName,
+				groupName,
+				groupConfig,
+				groupPullInterval(clusterConfig, groupConfig, defaultInterval),
+				!hasScriptsSchedule,
+			)
+
+			if hasScriptsSchedule {
+				go o.pullGroupOnSchedule(
+					o.stopPulling,
+					clusterName,
+					groupName,
+					groupConfig,
+					time.Duration(groupConfig.ScriptsPullIntervalSeconds)*time.Second,
+					true,
+				)
+			}
+		}
+	}
+}
+
+// RequestPull starts pulling of all agents in background.
+// Returns false if request coalesced with pull already in progress.
+func (o *Observer) RequestPull() bool {
+	if !o.beginPull() {
+		return false
+	}
+
+	go func() {
+		defer o.endPull()
+		o.pullAllAgents()
+	}()
+
+	return true
+}
+
+// PullAgents fetches data with script lists from all agents and store it to internal struct.
+// Does nothing if pull already in progress.
 func (o *Observer) PullAgents() {
+	if !o.beginPull() {
+		return
+	}
+
+	defer o.endPull()
+
+	o.pullAllAgents()
+}
+
+func (o *Observer) beginPull() bool {
+	o.pullMutex.Lock()
+	defer o.pullMutex.Unlock()
+
+	if o.pullInProgress {
+		return false
+	}
+
+	o.pullInProgress = true
+
+	return true
+}
+
+func (o *Observer) endPull() {
+	o.pullMutex.Lock()
+	defer o.pullMutex.Unlock()
+
+	o.pullInProgress = false
+}
+
+// pullAllAgents pulls every node with script lists
+func (o *Observer) pullAllAgents() {
 	for clusterName, clusterConfig := range o.Clusters {
 		for groupName, groupConfig := range clusterConfig.Groups {
+			retryDeadline := o.unscheduledPullRetryDeadline(clusterName, groupName)
+
 			for _, host := range groupConfig.Hosts {
 				o.pullAgent(
 					groupConfig,
 					clusterName,
 					groupName,
 					host,
+					true,
+					retryDeadline,
 				)
 			}
 		}
 	}
+
+	o.notifyPullListeners()
+}
+
+// notifyPullListeners called after pull of all agents or of group on its schedule.
+// Groups pulled concurrently, so listeners called one at a time.
+func (o *Observer) notifyPullListeners() {
+	o.listenersMutex.Lock()
+	defer o.listenersMutex.Unlock()
+
+	for _, listener := range o.pullListeners {
+		listener()
+	}
 }
 
 func (o *Observer) pullAgent(
@@ -139,26 +379,130 @@ func (o *Observer) pullAgent(
 	clusterName string,
 	groupName string,
 	host string,
+	includeScripts bool,
+	retryDeadline time.Time,
 ) {
-	var observableNodeStatistics, err = o.fetchNodeStatistics(
-		groupConfig.UrlPattern,
+	// agents which can not return script lists pulled without them
+	if includeScripts && !o.getAgentInfo(clusterName, groupName, host).SupportsSection(AgentSectionScripts) {
+		includeScripts = false
+	}
+
+	// repeatedly failing nodes not polled while circuit breaker open
+	isPullAllowed, isTrialPull := o.beginNodePull(nodeKey{clusterName, groupName, host})
+	if !isPullAllowed {
+		return
+	}
+
+	var observableNodeStatistics, err = o.fetchNodeStatisticsWithRetry(
+		groupConfig,
 		host,
-		groupConfig.BasicAuthCredentials,
+		includeScripts,
+		!isTrialPull,
+		retryDeadline,
 	)
 
+	o.updateNodeHealth(clusterName, groupName, host, err)
+
 	if err != nil {
 		log.Println(fmt.Sprintf("%v", err))
 		return
 	}
 
+	o.pullFpmStatus(groupConfig, host, observableNodeStatistics)
+
+	for _, warning := range observableNodeStatistics.OpcacheStatistics.ParseWarnings {
+		log.Printf("Node %s: field '%s' of agent response ignored: %s", host, warning.Field, warning.Message)
+	}
+
+	o.statusesMutex.Lock()
+
+	// derive rates from previous sample of node counters
+	pulledAt := time.Now()
+	currentSample := newRateSample(observableNodeStatistics.OpcacheStatistics, pulledAt)
+	rateSampleKey := nodeKey{clusterName, groupName, host}
+
+	if o.rateSamples == nil {
+		o.rateSamples = map[nodeKey]rateSample{}
+	}
+
+	if previousSample, ok := o.rateSamples[rateSampleKey]; ok {
+		observableNodeStatistics.OpcacheStatistics.Rates = calculateRates(previousSample, currentSample)
+	}
+
+	o.rateSamples[rateSampleKey] = currentSample
+
+	// merge with script list of previous full pull
+	observableNodeStatistics.OpcacheStatistics.StatusUpdatedAt = pulledAt
+
+	nodeState := observableNodeStatistics.OpcacheStatistics.State
+
+	// script list of node without cached scripts is known to be empty without pulling it
+	if includeScripts || nodeState == NodeStateEmpty || nodeState == NodeStateDisabled {
+		if observableNodeStatistics.OpcacheStatistics.Scripts == nil {
+			observableNodeStatistics.OpcacheStatistics.Scripts = map[string]Script{}
+		}
+
+		observableNodeStatistics.OpcacheStatistics.ScriptsUpdatedAt = pulledAt
+	} else {
+		previousStatus := o.opcacheStatuses[clusterName][groupName][host]
+		observableNodeStatistics.OpcacheStatistics.Scripts = previousStatus.Scripts
+		observableNodeStatistics.OpcacheStatistics.ScriptsUpdatedAt = previousStatus.ScriptsUpdatedAt
+	}
+
+	// remember capabilities of agent to adapt following requests
+	if o.agentsInfo == nil {
+		o.agentsInfo = map[nodeKey]AgentInfo{}
+	}
+
+	previousAgentInfo, wasPulled := o.agentsInfo[rateSampleKey]
+	if observableNodeStatistics.Agent.Outdated && (!wasPulled || previousAgentInfo.ProtocolVersion != observableNodeStatistics.Agent.ProtocolVersion) {
+		log.Printf(
+			"Agent of node %s uses protocol version %d, latest version is %d",
+			host,
+			observableNodeStatistics.Agent.ProtocolVersion,
+			AgentProtocolVersion,
+		)
+	}
+
+	o.agentsInfo[rateSampleKey] = observableNodeStatistics.Agent
+
 	// add fetched node opcache status to collection
 	o.opcacheStatuses[clusterName][groupName][host] = observableNodeStatistics.OpcacheStatistics
 
 	// add fetched node APCu status to collection
 	o.apcuStatuses[clusterName][groupName][host] = observableNodeStatistics.ApcuStatistics
 
+	// add fetched node PHP-FPM pool status to collection
+	if o.fpmStatuses[clusterName][groupName] != nil {
+		if observableNodeStatistics.FpmStatistics != nil {
+			o.fpmStatuses[clusterName][groupName][host] = *observableNodeStatistics.FpmStatistics
+		} else {
+			// status of unavailable page is unknown, so previous one not served
+			delete(o.fpmStatuses[clusterName][groupName], host)
+		}
+	}
+
 	// set last update time
-	o.LastStatusUpdate = time.Now()
+	o.lastStatusUpdate = pulledAt
+
+	o.statusesMutex.Unlock()
+
+	// notify listeners
+	nodeUpdate := NodeUpdate{
+		OpcacheStatistics: observableNodeStatistics.OpcacheStatistics,
+		ApcuStatistics:    observableNodeStatistics.ApcuStatistics,
+		FpmStatistics:     observableNodeStatistics.FpmStatistics,
+		ScriptsCount:      len(observableNodeStatistics.OpcacheStatistics.Scripts),
+	}
+	nodeUpdate.OpcacheStatistics.Scripts = nil
+
+	o.emitEvent(Event{
+		Type:        EventNodeUpdated,
+		ClusterName: clusterName,
+		GroupName:   groupName,
+		HostName:    host,
+		Data:        nodeUpdate,
+	})
 
 	// track metrics
 	for _, metricSender := range o.metricSenders {
@@ -167,51 +511,64 @@ func (o *Observer) pullAgent(
 }
 
 func (o *Observer) fetchNodeStatistics(
-	urlPattern string,
+	groupConfig configuration.GroupConfig,
 	host string,
-	basicAuthCredentials *configuration.BasicAuthCredentials,
+	includeScripts bool,
 ) (*NodeStatistics, error) {
-	// build agent url
-	pullAgentURL := o.buildPullAgentUrl(urlPattern, host) + "?scripts=1"
-	log.Printf(fmt.Sprintf("Observing %s", pullAgentURL))
-
-	// build request
-	httpClient := &http.Client{}
-	request, error := http.NewRequest("GET", pullAgentURL, nil)
-
-	if basicAuthCredentials != nil {
-		request.SetBasicAuth(basicAuthCredentials.User, basicAuthCredentials.Password)
-	}
+	log.Printf("Observing %s", host)
 
 	// send request
-	response, error := httpClient.Do(request)
+	response, error := o.getAgentTransport().FetchStatus(groupConfig, host, includeScripts)
 
 	if error != nil {
 		return nil, error
 	}
 
+	defer closeResponseBody(response.Body)
+
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Observable node return error %s", response.Status)
+		return nil, &agentStatusError{statusCode: response.StatusCode, status: response.Status}
 	}
 
-	body, error := ioutil.ReadAll(response.Body)
-
-	if error != nil {
-		return nil, error
+	if response.ContentLength > o.getMaxResponseSize() {
+		return nil, ErrResponseTooLarge
 	}
 
-	var observableNodeStatistics, err = o.parser.Parse(body)
+	// parse response while reading it
+	var observableNodeStatistics, err = o.parser.ParseReader(
+		newLimitedReader(response.Body, o.getMaxResponseSize()),
+	)
 
 	if err != nil {
-		return nil, fmt.Errorf("Can not parse response: %v", err)
+		return nil, fmt.Errorf("Invalid response of node %s: %w", host, err)
 	}
 
 	return observableNodeStatistics, nil
 }
 
-func (o *Observer) buildPullAgentUrl(urlPattern string, host string) string {
-	urlPatternReplacer := strings.NewReplacer("{host}", host)
-	agentURL := urlPatternReplacer.Replace(urlPattern)
+// getAgentTransport returns configured transport, or transport selected by group configuration
+func (o *Observer) getAgentTransport() AgentTransportInterface {
+	o.transportOnce.Do(func() {
+		if o.agentTransport == nil {
+			o.agentTransport = newGroupAgentTransport(o.getHTTPClient())
+		}
+	})
+
+	return o.agentTransport
+}
+
+func (o *Observer) getHTTPClient() *http.Client {
+	o.httpClientOnce.Do(func() {
+		o.httpClient = newAgentHTTPClient()
+	})
+
+	return o.httpClient
+}
+
+func (o *Observer) getMaxResponseSize() int64 {
+	if o.MaxResponseSizeBytes <= 0 {
+		return DefaultMaxResponseSizeBytes
+	}
 
-	return agentURL
+	return o.MaxResponseSizeBytes
 }
@@ -0,0 +1,291 @@
+package observer
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+const (
+	testClusterName = "production"
+	testGroupName   = "web"
+)
+
+// newTestObserver builds observer of single group pulled through fake transport
+func newTestObserver(t *testing.T, hosts ...string) (*Observer, *fakeAgentTransport) {
+	t.Helper()
+
+	o := NewObserver(map[string]configuration.ClusterConfig{
+		testClusterName: {
+			Groups: map[string]configuration.GroupConfig{
+				testGroupName: {
+					UrlPattern: "http://{host}/agent.php",
+					Hosts:      hosts,
+				},
+			},
+		},
+	})
+
+	agentTransport := newFakeAgentTransport()
+	o.SetAgentTransport(agentTransport)
+	o.initStatuses()
+
+	return o, agentTransport
+}
+
+func readFixture(t *testing.T, fixture string) string {
+	t.Helper()
+
+	response, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(response)
+}
+
+// waitForPull returns channel receiving value after every completed pull
+func waitForPull(o *Observer) <-chan struct{} {
+	pulled := make(chan struct{}, 1)
+
+	o.AddPullCompleteListener(func() {
+		select {
+		case pulled <- struct{}{}:
+		default:
+		}
+	})
+
+	return pulled
+}
+
+func receivePull(t *testing.T, pulled <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-pulled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("node not pulled")
+	}
+}
+
+func nodeRequests(agentTransport *fakeAgentTransport, host string, command string) []fakeAgentRequest {
+	requests := []fakeAgentRequest{}
+
+	for _, request := range agentTransport.GetRequests() {
+		if request.Host == host && request.Command == command {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests
+}
+
+func TestPullAgents(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1", "web2")
+	agentTransport.SetStatusResponse("web1", http.StatusOK, readFixture(t, "php82-preload.json"))
+	agentTransport.SetStatusResponse("web2", http.StatusOK, readFixture(t, "php74-without-scripts.json"))
+
+	startedAt := time.Now()
+	o.PullAgents()
+
+	if lastStatusUpdate := o.GetLastStatusUpdate(); lastStatusUpdate.Before(startedAt) {
+		t.Errorf("got last status update %s before pull", lastStatusUpdate)
+	}
+
+	statuses := o.GetOpcacheStatistics()[testClusterName][testGroupName]
+
+	if statuses["web1"].State != NodeStateDegraded || len(statuses["web1"].Scripts) != 3 {
+		t.Errorf("web1: got state %s with %d scripts", statuses["web1"].State, len(statuses["web1"].Scripts))
+	}
+
+	if statuses["web2"].State != NodeStateEnabled || statuses["web2"].PHPVersion != "7.4.33" {
+		t.Errorf("web2: got state %s of PHP %q", statuses["web2"].State, statuses["web2"].PHPVersion)
+	}
+
+	if !o.GetApcuStatistics()[testClusterName][testGroupName]["web1"].Enabled {
+		t.Error("APCu status of web1 not stored")
+	}
+
+	for _, host := range []string{"web1", "web2"} {
+		health := o.GetNodesHealth()[testClusterName][testGroupName][host]
+		if !health.Healthy || health.CircuitBreaker != CircuitBreakerClosed {
+			t.Errorf("%s: got health %+v", host, health)
+		}
+
+		requests := nodeRequests(agentTransport, host, "")
+		if len(requests) != 1 || !requests[0].IncludeScripts {
+			t.Errorf("%s: expected single status request with scripts, got %+v", host, requests)
+		}
+	}
+
+	if agentInfo := o.GetAgentsInfo()[testClusterName][testGroupName]["web1"]; agentInfo.ProtocolVersion != AgentProtocolVersion {
+		t.Errorf("web1: got agent protocol %d", agentInfo.ProtocolVersion)
+	}
+}
+
+func TestPullAgentsFailure(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1", "web2")
+	o.CircuitBreaker = configuration.CircuitBreakerConfig{FailureThreshold: 2, OpenIntervalSeconds: 60}
+	agentTransport.SetStatusResponse("web1", http.StatusOK, readFixture(t, "php80-jit.json"))
+	agentTransport.SetStatusError("web2", errors.New("connection refused"))
+
+	// third pull of node with open circuit breaker skipped
+	for i := 0; i < 3; i++ {
+		o.PullAgents()
+	}
+
+	health := o.GetNodesHealth()[testClusterName][testGroupName]["web2"]
+
+	if health.Healthy || health.Error != "connection refused" {
+		t.Errorf("web2: got health %+v", health)
+	}
+
+	if health.ConsecutiveFailures != 2 || health.CircuitBreaker != CircuitBreakerOpen {
+		t.Errorf("web2: got %d failures with %s circuit breaker", health.ConsecutiveFailures, health.CircuitBreaker)
+	}
+
+	if requests := nodeRequests(agentTransport, "web2", ""); len(requests) != 2 {
+		t.Errorf("web2: expected 2 status requests, got %d", len(requests))
+	}
+
+	if state := o.GetOpcacheStatistics()[testClusterName][testGroupName]["web2"].State; state != "" {
+		t.Errorf("web2: status of failed node stored with state %s", state)
+	}
+
+	// failure of one node does not affect others
+	if health := o.GetNodesHealth()[testClusterName][testGroupName]["web1"]; !health.Healthy {
+		t.Errorf("web1: got health %+v", health)
+	}
+
+	if requests := nodeRequests(agentTransport, "web1", ""); len(requests) != 3 {
+		t.Errorf("web1: expected 3 status requests, got %d", len(requests))
+	}
+}
+
+func TestResetOpcache(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1")
+	agentTransport.SetStatusResponse("web1", http.StatusOK, readFixture(t, "php83-after-reset.json"))
+	agentTransport.SetCommandResponse("web1", AgentCommandReset, http.StatusOK, `{"error":null}`)
+
+	events := []Event{}
+	o.AddEventListener(func(event Event) {
+		events = append(events, event)
+	})
+
+	if err := o.ResetOpcache(testClusterName, testGroupName, "web1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if requests := nodeRequests(agentTransport, "web1", AgentCommandReset); len(requests) != 1 {
+		t.Errorf("expected single reset command, got %d", len(requests))
+	}
+
+	// node pulled right after reset
+	if state := o.GetOpcacheStatistics()[testClusterName][testGroupName]["web1"].State; state != NodeStateEmpty {
+		t.Errorf("got state %s after reset", state)
+	}
+
+	lastEvent := events[len(events)-1]
+	if lastEvent.Type != EventResetCompleted || !lastEvent.Data.(ResetResult).Success {
+		t.Errorf("got last event %+v", lastEvent)
+	}
+}
+
+func TestResetOpcacheFailure(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1")
+	agentTransport.SetCommandResponse("web1", AgentCommandReset, http.StatusInternalServerError, "")
+
+	if err := o.ResetOpcache(testClusterName, testGroupName, "web1"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if requests := nodeRequests(agentTransport, "web1", ""); len(requests) != 0 {
+		t.Errorf("node pulled after failed reset: %+v", requests)
+	}
+}
+
+func TestInvalidateScript(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1")
+	agentTransport.SetStatusResponse("web1", http.StatusOK, readFixture(t, "php80-jit.json"))
+	agentTransport.SetCommandResponse("web1", AgentCommandInvalidate, http.StatusOK, `{"error":null}`)
+
+	pulled := waitForPull(o)
+
+	if err := o.InvalidateScript(testClusterName, testGroupName, "web1", "/var/www/src/Kernel.php"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requests := nodeRequests(agentTransport, "web1", AgentCommandInvalidate)
+	if len(requests) != 1 || requests[0].Parameters.Get("script") != "/var/www/src/Kernel.php" {
+		t.Fatalf("expected invalidate command of script, got %+v", requests)
+	}
+
+	// node refreshed in background
+	receivePull(t, pulled)
+
+	if state := o.GetOpcacheStatistics()[testClusterName][testGroupName]["web1"].State; state != NodeStateEnabled {
+		t.Errorf("got state %s after invalidate", state)
+	}
+}
+
+func TestInvalidateScriptFailure(t *testing.T) {
+	testCases := []struct {
+		name        string
+		statusCode  int
+		body        string
+		expectedErr string
+	}{
+		{
+			name:        "script not found",
+			statusCode:  http.StatusNotFound,
+			body:        `{"error":"Script not found"}`,
+			expectedErr: "Node web1 returned error: Script not found",
+		},
+		{
+			name:        "agent failure",
+			statusCode:  http.StatusInternalServerError,
+			body:        "",
+			expectedErr: "Observable node return error 500 Internal Server Error",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			o, agentTransport := newTestObserver(t, "web1")
+			agentTransport.SetCommandResponse("web1", AgentCommandInvalidate, testCase.statusCode, testCase.body)
+
+			err := o.InvalidateScript(testClusterName, testGroupName, "web1", "/var/www/missing.php")
+			if err == nil || err.Error() != testCase.expectedErr {
+				t.Errorf("got error %v, want %q", err, testCase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestInvalidateScriptNotSupported(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1")
+	agentTransport.SetStatusResponse(
+		"web1",
+		http.StatusOK,
+		`{"protocol":{"version":2,"commands":["status"],"sections":["configuration","status"]},"status":false}`,
+	)
+
+	o.PullAgents()
+
+	if err := o.InvalidateScript(testClusterName, testGroupName, "web1", "/var/www/index.php"); err != ErrCommandNotSupported {
+		t.Errorf("got error %v, want %v", err, ErrCommandNotSupported)
+	}
+
+	if err := o.InvalidateScript(testClusterName, testGroupName, "web9", "/var/www/index.php"); err != ErrNodeNotFound {
+		t.Errorf("got error %v, want %v", err, ErrNodeNotFound)
+	}
+
+	if requests := nodeRequests(agentTransport, "web1", AgentCommandInvalidate); len(requests) != 0 {
+		t.Errorf("unsupported command sent to agent: %+v", requests)
+	}
+}
@@ -1,5 +1,7 @@
 package observer
 
+import "time"
+
 // ClustersOpcacheStatuses represents collection of node opcache statuses
 // Struct: {clusterName}.{groupName}.{nodeName} => NodeOpcacheStatus
 type ClustersOpcacheStatuses map[string]map[string]map[string]NodeOpcacheStatus
@@ -7,8 +9,38 @@ type ClustersOpcacheStatuses map[string]map[string]map[string]NodeOpcacheStatus
 // https://github.com/php/php-src/blob/master/ext/opcache/zend_accelerator_hash.c
 var primeNumbers = []int{5, 11, 19, 53, 107, 223, 463, 983, 1979, 3907, 7963, 16229, 32531, 65407, 130987, 262237, 524521, 1048793}
 
+// AcceleratorHashSize returns real size of opcache hash table which PHP
+// allocates for configured opcache.max_accelerated_files value
+func AcceleratorHashSize(maxAcceleratedFiles int) int {
+	for _, primeNumber := range primeNumbers {
+		if maxAcceleratedFiles <= primeNumber {
+			return primeNumber
+		}
+	}
+
+	return primeNumbers[len(primeNumbers)-1]
+}
+
+// NodeState describes whether opcache of node serves scripts
+type NodeState string
+
+const (
+	// NodeStateEnabled means opcache enabled and caches scripts
+	NodeStateEnabled NodeState = "enabled"
+	// NodeStateDisabled means opcache loaded but disabled by opcache.enable=0
+	NodeStateDisabled NodeState = "disabled"
+	// NodeStateEmpty means opcache enabled but nothing cached yet, e.g. after reset or start of FPM pool
+	NodeStateEmpty NodeState = "empty"
+	// NodeStateDegraded means opcache enabled but can not cache new scripts: cache is full or restart pending
+	NodeStateDegraded NodeState = "degraded"
+)
+
+// NodeStates lists all states of node
+var NodeStates = []NodeState{NodeStateEnabled, NodeStateDisabled, NodeStateEmpty, NodeStateDegraded}
+
 // NodeOpcacheStatus represents status of opcache on single node
 type NodeOpcacheStatus struct {
+	State         NodeState
 	Configuration map[string]interface{}
 	PHPVersion    string // configuration.version.version
 	Scripts       map[string]Script
@@ -23,6 +55,25 @@ type NodeOpcacheStatus struct {
 	Keys                 Keys
 	KeyHits              KeyHits
 	Restarts             Restarts
+	// JIT status, nil when not reported by PHP before 8.0
+	Jit *JitStatus
+	// Preloading status, nil when opcache.preload not configured
+	Preload *NodePreloadStatus
+	// Rates derived from difference with previous pull, nil until two pulls made
+	Rates *Rates
+	// Time of last pull of status counters and of script list, which may be pulled on different schedules
+	StatusUpdatedAt  time.Time
+	ScriptsUpdatedAt time.Time
+	// Fields of agent response which were absent or malformed, so defaults used
+	ParseWarnings []ParseWarning
+}
+
+// NodePreloadStatus represents scripts, functions and classes preloaded by opcache.preload script
+type NodePreloadStatus struct {
+	MemoryConsumption int      // status.preload_statistics.memory_consumption
+	FunctionsCount    int      // count of status.preload_statistics.functions
+	ClassesCount      int      // count of status.preload_statistics.classes
+	Scripts           []string // status.preload_statistics.scripts
 }
 
 type Memory struct {
@@ -65,6 +116,20 @@ type Restarts struct {
 	LastRestartTime  int64 // status.opcache_statistics.last_restart_time
 }
 
+// Rates represents change of lifetime counters between two pulls
+type Rates struct {
+	IntervalSeconds float64
+	HitsPerSecond   float64
+	MissesPerSecond float64
+	// Hits to all requests of scripts within interval
+	HitRatio               float64
+	NewOutOfMemoryRestarts int
+	NewHashRestarts        int
+	NewManualRestarts      int
+	// CountersReset is true when opcache restarted within interval and counters were zeroed
+	CountersReset bool
+}
+
 // Script represents info abount signle script on one node
 type Script struct {
 	Hits              int
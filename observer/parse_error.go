@@ -0,0 +1,100 @@
+package observer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseError describes field of agent response which can not be parsed
+type ParseError struct {
+	Field string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("Can not parse agent response: %v", e.Err)
+	}
+
+	return fmt.Sprintf("Can not parse field '%s' of agent response: %v", e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseWarning describes field of agent response which was absent or malformed,
+// so default value used instead. Such fields differ between PHP versions.
+type ParseWarning struct {
+	Field   string
+	Message string
+}
+
+// parseWarnings collects warnings during parsing of single agent response
+type parseWarnings []ParseWarning
+
+func (w *parseWarnings) add(field string, message string) {
+	*w = append(*w, ParseWarning{Field: field, Message: message})
+}
+
+// decodeSection decodes next value of stream to target. Values of wrong type are
+// recorded as warnings and skipped, other errors reported as ParseError of field.
+func (w *parseWarnings) decodeSection(decoder *json.Decoder, field string, target interface{}) error {
+	return w.sectionError(field, decoder.Decode(target))
+}
+
+// sectionError classifies error of decoding of field, so name of field built only on failure
+func (w *parseWarnings) sectionError(field string, err error) error {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		warningField := field
+		if typeError.Field != "" {
+			warningField = field + "." + typeError.Field
+		}
+
+		w.add(warningField, fmt.Sprintf("Unexpected %s value", typeError.Value))
+
+		return nil
+	}
+
+	if err != nil {
+		return &ParseError{Field: field, Err: err}
+	}
+
+	return nil
+}
+
+// directiveNumber reads numeric directive which may be encoded as number, boolean or numeric string.
+// Absent or malformed directive recorded as warning and zero returned.
+func (w *parseWarnings) directiveNumber(directives map[string]interface{}, directive string) float64 {
+	field := "configuration.directives." + directive
+
+	rawValue, ok := directives[directive]
+	if !ok {
+		w.add(field, "Directive not found")
+		return 0
+	}
+
+	switch value := rawValue.(type) {
+	case float64:
+		return value
+	case bool:
+		if value {
+			return 1
+		}
+
+		return 0
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil {
+			return number
+		}
+	}
+
+	w.add(field, fmt.Sprintf("Expected number but got %v", rawValue))
+
+	return 0
+}
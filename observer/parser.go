@@ -1,8 +1,11 @@
 package observer
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 )
 
 // AgentMessageParser implements parsing of agent response
@@ -10,6 +13,14 @@ type AgentMessageParser struct {
 }
 
 type agentMessage struct {
+	// Reported by agents since protocol version 2 as first section of message
+	Protocol struct {
+		Version  int      `json:"version"`
+		Commands []string `json:"commands"`
+		Sections []string `json:"sections"`
+	} `json:"protocol"`
+	// protocol section found in message
+	hasProtocol   bool
 	Configuration struct {
 		Directives map[string]interface{} `json:"directives"`
 		Version    struct {
@@ -17,7 +28,12 @@ type agentMessage struct {
 		} `json:"version"`
 	} `json:"configuration"`
 	Status struct {
-		CacheFull         bool `son:"cache_full"`
+		// set when opcache disabled and opcache_get_status() returned false instead of status
+		disabled          bool
+		OpcacheEnabled    *bool `json:"opcache_enabled"`
+		CacheFull         bool  `json:"cache_full"`
+		RestartPending    bool  `json:"restart_pending"`
+		RestartInProgress bool  `json:"restart_in_progress"`
 		OpcacheStatistics struct {
 			StartTime                int64 `json:"start_time"`
 			TotalPrime               int   `json:"max_cached_keys"`
@@ -42,24 +58,47 @@ type agentMessage struct {
 			FreeMemory   int `json:"free_memory"`
 			NumOfStrings int `json:"number_of_strings"`
 		} `json:"interned_strings_usage"`
-		Scripts map[string]struct {
-			Hits              int   `json:"hits"`
-			CreateTimestamp   int64 `json:"timestamp"`
-			LastUsedTimestamp int64 `json:"last_used_timestamp"`
-			Memory            int   `json:"memory_consumption"`
-		} `json:"scripts"`
+		Jit *struct {
+			Enabled    bool `json:"enabled"`
+			On         bool `json:"on"`
+			Kind       int  `json:"kind"`
+			OptLevel   int  `json:"opt_level"`
+			OptFlags   int  `json:"opt_flags"`
+			BufferSize int  `json:"buffer_size"`
+			BufferFree int  `json:"buffer_free"`
+		} `json:"jit"`
+		PreloadStatistics *struct {
+			MemoryConsumption int      `json:"memory_consumption"`
+			Functions         []string `json:"functions"`
+			Classes           []string `json:"classes"`
+			Scripts           []string `json:"scripts"`
+		} `json:"preload_statistics"`
+		// scripts decoded one by one directly to result, nil when not requested
+		scripts map[string]Script
 	} `json:"status"`
+	// fields which were absent or malformed
+	warnings   parseWarnings
 	ApcuStatus struct {
 		Enabled bool      `json:"enabled"`
 		SmaInfo *struct { // null when APCu disabled
-			NumSeg     int `json:"num_seg"`
-			SegSize    int `json:"seg_size"`
-			AvailMem   int `json:"avail_mem"`
-			BlockLists [][]struct {
-				Size   int `json:"size"`
-				Offset int `json:"offset"`
-			} `json:"block_lists"`
+			NumSeg     int               `json:"num_seg"`
+			SegSize    int               `json:"seg_size"`
+			AvailMem   int               `json:"avail_mem"`
+			BlockLists [][]apcuFreeBlock `json:"block_lists"` // free blocks of every segment
 		}
+		// counters encoded as floats by APCu
+		CacheInfo *struct {
+			NumSlots   float64 `json:"num_slots"`
+			TTL        float64 `json:"ttl"`
+			NumEntries float64 `json:"num_entries"`
+			NumHits    float64 `json:"num_hits"`
+			NumMisses  float64 `json:"num_misses"`
+			NumInserts float64 `json:"num_inserts"`
+			Expunges   float64 `json:"expunges"`
+			StartTime  int64   `json:"start_time"`
+			MemSize    float64 `json:"mem_size"`
+			MemoryType string  `json:"memory_type"`
+		} `json:"cacheInfo"` // absent in responses of older agents
 		Settings *map[string]struct { // null when APCu disabled
 			GlobalValue string `json:"global_value"`
 			LocalValue  string `json:"local_value"`
@@ -68,40 +107,407 @@ type agentMessage struct {
 	} `json:"apcu"`
 }
 
+// protocolDecoder decodes parts of agent message which differ between protocol versions
+type protocolDecoder struct {
+	decodeScripts func(
+		parser AgentMessageParser,
+		decoder *json.Decoder,
+		scriptsCount int,
+		warnings *parseWarnings,
+	) (map[string]Script, error)
+}
+
+var protocolDecoders = map[int]protocolDecoder{
+	// script list keyed by path, PHP encodes empty list as array
+	1: {decodeScripts: AgentMessageParser.decodeScripts},
+	// script list is array of scripts with paths
+	2: {decodeScripts: AgentMessageParser.decodeScriptList},
+}
+
+type agentScript struct {
+	FullPath          string `json:"full_path"`
+	Hits              int    `json:"hits"`
+	CreateTimestamp   int64  `json:"timestamp"`
+	LastUsedTimestamp int64  `json:"last_used_timestamp"`
+	Memory            int    `json:"memory_consumption"`
+}
+
+func (script agentScript) toScript() Script {
+	return Script{
+		Hits:              script.Hits,
+		CreateTimestamp:   script.CreateTimestamp,
+		LastUsedTimestamp: script.LastUsedTimestamp,
+		Memory:            script.Memory,
+	}
+}
+
 // Parse agent response to struct
 func (parser AgentMessageParser) Parse(body []byte) (*NodeStatistics, error) {
+	return parser.ParseReader(bytes.NewReader(body))
+}
+
+// ParseReader parses agent response from stream without buffering it whole.
+// Script list decoded incrementally, so memory used only for resulting statistics.
+// Absent or malformed fields do not fail parsing, but reported as warnings of node status.
+func (parser AgentMessageParser) ParseReader(reader io.Reader) (*NodeStatistics, error) {
 	var agentMessage = agentMessage{}
-	err := json.Unmarshal(body, &agentMessage)
+	agentMessage.Protocol.Version = LegacyAgentProtocolVersion
+
+	err := parser.decodeAgentMessage(json.NewDecoder(reader), &agentMessage)
 	if err != nil {
 		return nil, err
 	}
 
-	opcacheStatus, err := parser.buildNodeOpcacheStatus(agentMessage)
+	opcacheStatus, err := parser.buildNodeOpcacheStatus(&agentMessage)
 	if err != nil {
 		return nil, err
 	}
 
-	apcuStatus, err := parser.buildNodeApcuStatus(agentMessage)
+	apcuStatus, err := parser.buildNodeApcuStatus(&agentMessage)
 	if err != nil {
 		return nil, err
 	}
 
+	opcacheStatus.ParseWarnings = agentMessage.warnings
+
 	nodeStatus := NodeStatistics{
 		OpcacheStatistics: *opcacheStatus,
 		ApcuStatistics:    *apcuStatus,
+		Agent:             parser.buildAgentInfo(&agentMessage),
 	}
 
 	return &nodeStatus, nil
 }
 
-func (parser AgentMessageParser) buildNodeOpcacheStatus(agentMessage agentMessage) (*NodeOpcacheStatus, error) {
-	if len(agentMessage.Status.Scripts) == 0 {
-		return nil, errors.New("No scripts found in agent response")
+func (parser AgentMessageParser) decodeAgentMessage(decoder *json.Decoder, agentMessage *agentMessage) error {
+	warnings := &agentMessage.warnings
+	foundSections := map[string]bool{}
+
+	err := decodeObject(decoder, "", func(key string) error {
+		foundSections[key] = true
+
+		switch key {
+		case "protocol":
+			if len(foundSections) > 1 {
+				return &ParseError{Field: key, Err: errors.New("Protocol must be first section of message")}
+			}
+
+			agentMessage.hasProtocol = true
+
+			return warnings.decodeSection(decoder, key, &agentMessage.Protocol)
+		case "configuration":
+			return warnings.decodeSection(decoder, key, &agentMessage.Configuration)
+		case "apcu":
+			return warnings.decodeSection(decoder, key, &agentMessage.ApcuStatus)
+		case "status":
+			return parser.decodeStatus(decoder, agentMessage)
+		default:
+			return skipValue(decoder)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, section := range []string{"configuration", "status"} {
+		if !foundSections[section] {
+			warnings.add(section, "Section not found")
+		}
+	}
+
+	return nil
+}
+
+func (parser AgentMessageParser) decodeStatus(decoder *json.Decoder, agentMessage *agentMessage) error {
+	status := &agentMessage.Status
+	warnings := &agentMessage.warnings
+
+	token, err := decoder.Token()
+	if err != nil {
+		return &ParseError{Field: "status", Err: err}
+	}
+
+	if token == false {
+		status.disabled = true
+		return nil
+	}
+
+	// status could not be read by agent, counters left empty like when section absent
+	if token == nil {
+		warnings.add("status", "Unexpected null value")
+		return nil
+	}
+
+	return decodeObjectBody(decoder, "status", token, func(key string) error {
+		field := "status." + key
+
+		switch key {
+		case "opcache_enabled":
+			return warnings.decodeSection(decoder, field, &status.OpcacheEnabled)
+		case "restart_pending":
+			return warnings.decodeSection(decoder, field, &status.RestartPending)
+		case "restart_in_progress":
+			return warnings.decodeSection(decoder, field, &status.RestartInProgress)
+		case "cache_full":
+			return warnings.decodeSection(decoder, field, &status.CacheFull)
+		case "jit":
+			return warnings.decodeSection(decoder, field, &status.Jit)
+		case "preload_statistics":
+			return warnings.decodeSection(decoder, field, &status.PreloadStatistics)
+		case "opcache_statistics":
+			return warnings.decodeSection(decoder, field, &status.OpcacheStatistics)
+		case "memory_usage":
+			return warnings.decodeSection(decoder, field, &status.MemoryUsage)
+		case "interned_strings_usage":
+			return warnings.decodeSection(decoder, field, &status.InternedStringsUsage)
+		case "scripts":
+			scripts, err := parser.protocolDecoderOf(agentMessage).decodeScripts(
+				parser,
+				decoder,
+				scriptsCountHint(status.OpcacheStatistics.UsedScripts),
+				warnings,
+			)
+			status.scripts = scripts
+			return err
+		default:
+			return skipValue(decoder)
+		}
+	})
+}
+
+// protocolDecoderOf returns decoder of protocol version reported by agent.
+// Agents of newer protocol decoded as latest known version.
+func (parser AgentMessageParser) protocolDecoderOf(agentMessage *agentMessage) protocolDecoder {
+	if decoder, ok := protocolDecoders[agentMessage.Protocol.Version]; ok {
+		return decoder
+	}
+
+	agentMessage.warnings.add(
+		"protocol.version",
+		fmt.Sprintf("Unknown protocol version %d, decoded as version %d", agentMessage.Protocol.Version, AgentProtocolVersion),
+	)
+
+	return protocolDecoders[AgentProtocolVersion]
+}
+
+func (parser AgentMessageParser) buildAgentInfo(agentMessage *agentMessage) AgentInfo {
+	if !agentMessage.hasProtocol {
+		return legacyAgentInfo()
+	}
+
+	return AgentInfo{
+		ProtocolVersion: agentMessage.Protocol.Version,
+		Commands:        agentMessage.Protocol.Commands,
+		Sections:        agentMessage.Protocol.Sections,
+		Outdated:        agentMessage.Protocol.Version < AgentProtocolVersion,
+	}
+}
+
+// decodeScriptList decodes script list of protocol version 2, which is array of scripts with paths
+func (parser AgentMessageParser) decodeScriptList(
+	decoder *json.Decoder,
+	scriptsCount int,
+	warnings *parseWarnings,
+) (map[string]Script, error) {
+	scripts := make(map[string]Script, scriptsCount)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, &ParseError{Field: "status.scripts", Err: err}
+	}
+
+	if token != json.Delim('[') {
+		return nil, &ParseError{Field: "status.scripts", Err: fmt.Errorf("Expected array but got %v", token)}
+	}
+
+	for scriptIndex := 0; decoder.More(); scriptIndex++ {
+		var script agentScript
+		if err := decoder.Decode(&script); err != nil {
+			if err := warnings.sectionError(fmt.Sprintf("status.scripts.%d", scriptIndex), err); err != nil {
+				return nil, err
+			}
+		}
+
+		if script.FullPath == "" {
+			warnings.add(fmt.Sprintf("status.scripts.%d.full_path", scriptIndex), "Path of script not found")
+			continue
+		}
+
+		scripts[script.FullPath] = script.toScript()
+	}
+
+	// closing bracket
+	if _, err := decoder.Token(); err != nil {
+		return nil, &ParseError{Field: "status.scripts", Err: err}
+	}
+
+	return scripts, nil
+}
+
+// decodeScripts decodes script list of legacy protocol entry by entry.
+// PHP encodes empty list as array, so both object and empty array accepted.
+// Count of cached scripts reported before list, so map allocated once.
+func (parser AgentMessageParser) decodeScripts(
+	decoder *json.Decoder,
+	scriptsCount int,
+	warnings *parseWarnings,
+) (map[string]Script, error) {
+	scripts := make(map[string]Script, scriptsCount)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, &ParseError{Field: "status.scripts", Err: err}
+	}
+
+	if token == json.Delim('[') {
+		if decoder.More() {
+			return nil, &ParseError{Field: "status.scripts", Err: errors.New("Expected object but got array")}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, &ParseError{Field: "status.scripts", Err: err}
+		}
+
+		return scripts, nil
+	}
+
+	if token != json.Delim('{') {
+		return nil, &ParseError{Field: "status.scripts", Err: fmt.Errorf("Expected object but got %v", token)}
+	}
+
+	for decoder.More() {
+		keyToken, err := decoder.Token()
+		if err != nil {
+			return nil, &ParseError{Field: "status.scripts", Err: err}
+		}
+
+		phpFilePath, ok := keyToken.(string)
+		if !ok {
+			return nil, &ParseError{Field: "status.scripts", Err: fmt.Errorf("Unexpected script key %v", keyToken)}
+		}
+
+		var script agentScript
+		if err := decoder.Decode(&script); err != nil {
+			if err := warnings.sectionError("status.scripts."+phpFilePath, err); err != nil {
+				return nil, err
+			}
+		}
+
+		scripts[phpFilePath] = script.toScript()
+	}
+
+	// closing brace
+	if _, err := decoder.Token(); err != nil {
+		return nil, &ParseError{Field: "status.scripts", Err: err}
+	}
+
+	return scripts, nil
+}
+
+// scriptsCountHint limits reported count of scripts by size of biggest opcache hash table,
+// so malformed counter can not cause huge allocation
+func scriptsCountHint(usedScripts int) int {
+	if usedScripts < 0 {
+		return 0
+	}
+
+	if maxScripts := primeNumbers[len(primeNumbers)-1]; usedScripts > maxScripts {
+		return maxScripts
+	}
+
+	return usedScripts
+}
+
+// decodeObject iterates over keys of JSON object and calls handler which must consume value of key
+func decodeObject(decoder *json.Decoder, field string, handleKey func(key string) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return &ParseError{Field: field, Err: err}
+	}
+
+	return decodeObjectBody(decoder, field, token, handleKey)
+}
+
+// decodeObjectBody decodes JSON object which opening token already read
+func decodeObjectBody(decoder *json.Decoder, field string, token json.Token, handleKey func(key string) error) error {
+	// PHP encodes empty associative array as empty JSON array
+	if token == json.Delim('[') {
+		if decoder.More() {
+			return &ParseError{Field: field, Err: errors.New("Expected object but got array")}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return &ParseError{Field: field, Err: err}
+		}
+
+		return nil
+	}
+
+	if token != json.Delim('{') {
+		return &ParseError{Field: field, Err: fmt.Errorf("Expected object but got %v", token)}
+	}
+
+	for decoder.More() {
+		keyToken, err := decoder.Token()
+		if err != nil {
+			return &ParseError{Field: field, Err: err}
+		}
+
+		key, ok := keyToken.(string)
+		if !ok {
+			return &ParseError{Field: field, Err: fmt.Errorf("Unexpected object key %v", keyToken)}
+		}
+
+		if err := handleKey(key); err != nil {
+			return err
+		}
+	}
+
+	// closing brace
+	if _, err := decoder.Token(); err != nil {
+		return &ParseError{Field: field, Err: err}
+	}
+
+	return nil
+}
+
+// skipValue consumes next value without keeping it in memory
+func skipValue(decoder *json.Decoder) error {
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (parser AgentMessageParser) buildNodeOpcacheStatus(agentMessage *agentMessage) (*NodeOpcacheStatus, error) {
+	directives := agentMessage.Configuration.Directives
+	warnings := &agentMessage.warnings
+
+	// empty script list is valid state of node after reset, so script list absent only when not requested
+	scripts := agentMessage.Status.scripts
+	if scripts == nil && parser.isOpcacheDisabled(agentMessage) {
+		scripts = map[string]Script{}
 	}
 
 	// optimisations bitmap to int array
 	var optimisationsIntSlice = []int{}
-	var optimisationsBitmap = int(agentMessage.Configuration.Directives["opcache.optimization_level"].(float64))
+	var optimisationsBitmap = int(warnings.directiveNumber(directives, "opcache.optimization_level"))
 	for optimisationID := 0; optimisationID <= 16; optimisationID++ {
 		if ((1 << optimisationID) & optimisationsBitmap) != 0 {
 			optimisationsIntSlice = append(optimisationsIntSlice, optimisationID)
@@ -110,29 +516,30 @@ func (parser AgentMessageParser) buildNodeOpcacheStatus(agentMessage agentMessag
 
 	// build struct
 	opcacheStatus := NodeOpcacheStatus{
-		Configuration: agentMessage.Configuration.Directives,
+		State:         parser.nodeState(agentMessage),
+		Configuration: directives,
 		PHPVersion:    agentMessage.Configuration.Version.Version,
 		Optimizations: optimisationsIntSlice,
-		Scripts:       map[string]Script{},
+		Scripts:       scripts,
 		StartTime:     agentMessage.Status.OpcacheStatistics.StartTime,
 		CacheFull:     agentMessage.Status.CacheFull,
 		Memory: Memory{
-			Total:                   int(agentMessage.Configuration.Directives["opcache.memory_consumption"].(float64)),
+			Total:                   int(warnings.directiveNumber(directives, "opcache.memory_consumption")),
 			Used:                    agentMessage.Status.MemoryUsage.Used,
 			Free:                    agentMessage.Status.MemoryUsage.Free,
 			Wasted:                  agentMessage.Status.MemoryUsage.Wasted,
-			MaxWastedPercentage:     agentMessage.Configuration.Directives["opcache.max_wasted_percentage"].(float64),
+			MaxWastedPercentage:     warnings.directiveNumber(directives, "opcache.max_wasted_percentage"),
 			CurrentWastedPercentage: agentMessage.Status.MemoryUsage.CurrentWastedPercentage,
 		},
 		InternedStingsMemory: InternedStingsMemory{
-			Total:        int(agentMessage.Configuration.Directives["opcache.interned_strings_buffer"].(float64)) * 1024 * 1024,
+			Total:        int(warnings.directiveNumber(directives, "opcache.interned_strings_buffer")) * 1024 * 1024,
 			BufferSize:   agentMessage.Status.InternedStringsUsage.BufferSize,
 			UsedMemory:   agentMessage.Status.InternedStringsUsage.UsedMemory,
 			FreeMemory:   agentMessage.Status.InternedStringsUsage.FreeMemory,
 			NumOfStrings: agentMessage.Status.InternedStringsUsage.NumOfStrings,
 		},
 		Keys: Keys{
-			Total:       int(agentMessage.Configuration.Directives["opcache.max_accelerated_files"].(float64)),
+			Total:       int(warnings.directiveNumber(directives, "opcache.max_accelerated_files")),
 			TotalPrime:  agentMessage.Status.OpcacheStatistics.TotalPrime,
 			UsedKeys:    agentMessage.Status.OpcacheStatistics.UsedKeys,
 			UsedScripts: agentMessage.Status.OpcacheStatistics.UsedScripts,
@@ -150,34 +557,139 @@ func (parser AgentMessageParser) buildNodeOpcacheStatus(agentMessage agentMessag
 		},
 	}
 
-	for phpFilePath, script := range agentMessage.Status.Scripts {
-		opcacheStatus.Scripts[phpFilePath] = Script{
-			Hits:              script.Hits,
-			CreateTimestamp:   script.CreateTimestamp,
-			LastUsedTimestamp: script.LastUsedTimestamp,
-			Memory:            script.Memory,
+	if jit := agentMessage.Status.Jit; jit != nil {
+		opcacheStatus.Jit = &JitStatus{
+			Enabled:    jit.Enabled,
+			On:         jit.On,
+			Kind:       jit.Kind,
+			OptLevel:   jit.OptLevel,
+			OptFlags:   jit.OptFlags,
+			BufferSize: jit.BufferSize,
+			BufferFree: jit.BufferFree,
+			BufferUsed: jit.BufferSize - jit.BufferFree,
+		}
+
+		if jitDirective, ok := directives["opcache.jit"].(string); ok {
+			jitMode, err := DecodeJitMode(jitDirective)
+			if err != nil {
+				warnings.add("configuration.directives.opcache.jit", err.Error())
+			}
+
+			opcacheStatus.Jit.Mode = jitMode
+		}
+	}
+
+	if preloadStatistics := agentMessage.Status.PreloadStatistics; preloadStatistics != nil {
+		opcacheStatus.Preload = &NodePreloadStatus{
+			MemoryConsumption: preloadStatistics.MemoryConsumption,
+			FunctionsCount:    len(preloadStatistics.Functions),
+			ClassesCount:      len(preloadStatistics.Classes),
+			Scripts:           preloadStatistics.Scripts,
+		}
+
+		if opcacheStatus.Preload.Scripts == nil {
+			opcacheStatus.Preload.Scripts = []string{}
 		}
 	}
 
 	return &opcacheStatus, nil
 }
 
-func (parser AgentMessageParser) buildNodeApcuStatus(agentMessage agentMessage) (*NodeApcuStatus, error) {
+func (parser AgentMessageParser) isOpcacheDisabled(agentMessage *agentMessage) bool {
+	if agentMessage.Status.disabled {
+		return true
+	}
+
+	if agentMessage.Status.OpcacheEnabled != nil && !*agentMessage.Status.OpcacheEnabled {
+		return true
+	}
+
+	enabled, ok := agentMessage.Configuration.Directives["opcache.enable"].(bool)
+
+	return ok && !enabled
+}
+
+func (parser AgentMessageParser) nodeState(agentMessage *agentMessage) NodeState {
+	status := agentMessage.Status
+
+	if parser.isOpcacheDisabled(agentMessage) {
+		return NodeStateDisabled
+	}
+
+	if status.CacheFull || status.RestartPending || status.RestartInProgress {
+		return NodeStateDegraded
+	}
+
+	// counters always present, script list only when requested
+	if status.OpcacheStatistics.UsedScripts == 0 && len(status.scripts) == 0 {
+		return NodeStateEmpty
+	}
+
+	return NodeStateEnabled
+}
+
+func (parser AgentMessageParser) buildNodeApcuStatus(agentMessage *agentMessage) (*NodeApcuStatus, error) {
 	apcuStatus := NodeApcuStatus{
 		Enabled: agentMessage.ApcuStatus.Enabled,
 	}
 
 	if agentMessage.ApcuStatus.Enabled {
 		// sma info
-		apcuStatus.SmaInfo = &NodeApcuSmaInfo{
-			NumSeg:   agentMessage.ApcuStatus.SmaInfo.NumSeg,
-			SegSize:  agentMessage.ApcuStatus.SmaInfo.SegSize,
-			AvailMem: agentMessage.ApcuStatus.SmaInfo.AvailMem,
+		if agentMessage.ApcuStatus.SmaInfo != nil {
+			apcuStatus.SmaInfo = &NodeApcuSmaInfo{
+				NumSeg:   agentMessage.ApcuStatus.SmaInfo.NumSeg,
+				SegSize:  agentMessage.ApcuStatus.SmaInfo.SegSize,
+				AvailMem: agentMessage.ApcuStatus.SmaInfo.AvailMem,
+			}
+
+			if blockLists := agentMessage.ApcuStatus.SmaInfo.BlockLists; blockLists != nil {
+				allFreeBlocks := []apcuFreeBlock{}
+				apcuStatus.SmaInfo.SegmentsFragmentation = []NodeApcuFragmentation{}
+
+				for _, segmentFreeBlocks := range blockLists {
+					allFreeBlocks = append(allFreeBlocks, segmentFreeBlocks...)
+					apcuStatus.SmaInfo.SegmentsFragmentation = append(
+						apcuStatus.SmaInfo.SegmentsFragmentation,
+						analyzeApcuFragmentation(segmentFreeBlocks),
+					)
+				}
+
+				fragmentation := analyzeApcuFragmentation(allFreeBlocks)
+				apcuStatus.SmaInfo.Fragmentation = &fragmentation
+			}
+		} else {
+			apcuStatus.SmaInfo = &NodeApcuSmaInfo{}
+			agentMessage.warnings.add("apcu.smaInfo", "Section not found")
+		}
+
+		// cache info
+		if cacheInfo := agentMessage.ApcuStatus.CacheInfo; cacheInfo != nil {
+			apcuStatus.CacheInfo = &NodeApcuCacheInfo{
+				NumSlots:   int(cacheInfo.NumSlots),
+				TTL:        int(cacheInfo.TTL),
+				NumEntries: int(cacheInfo.NumEntries),
+				NumHits:    int(cacheInfo.NumHits),
+				NumMisses:  int(cacheInfo.NumMisses),
+				NumInserts: int(cacheInfo.NumInserts),
+				Expunges:   int(cacheInfo.Expunges),
+				StartTime:  cacheInfo.StartTime,
+				MemSize:    int(cacheInfo.MemSize),
+				MemoryType: cacheInfo.MemoryType,
+			}
+
+			if requests := cacheInfo.NumHits + cacheInfo.NumMisses; requests > 0 {
+				apcuStatus.CacheInfo.HitRatio = cacheInfo.NumHits / requests
+			}
 		}
 
 		// settings
 		apcuStatus.Settings = &map[string]NodeApcuSetting{}
 
+		if agentMessage.ApcuStatus.Settings == nil {
+			agentMessage.warnings.add("apcu.settings", "Section not found")
+			return &apcuStatus, nil
+		}
+
 		for settingName, setting := range *agentMessage.ApcuStatus.Settings {
 			(*apcuStatus.Settings)[settingName] = NodeApcuSetting{
 				GlobalValue: setting.GlobalValue,
@@ -0,0 +1,300 @@
+package observer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseReader(t *testing.T) {
+	testCases := []struct {
+		fixture         string
+		state           NodeState
+		phpVersion      string
+		scriptsCount    int
+		protocolVersion int
+		jit             bool
+		preload         bool
+		apcuEnabled     bool
+		apcuCacheInfo   bool
+		warnings        []ParseWarning
+	}{
+		{
+			fixture:         "php70-legacy.json",
+			state:           NodeStateEnabled,
+			phpVersion:      "7.0.33",
+			scriptsCount:    3,
+			protocolVersion: LegacyAgentProtocolVersion,
+		},
+		{
+			fixture:         "php72-restricted-api.json",
+			state:           NodeStateDisabled,
+			protocolVersion: LegacyAgentProtocolVersion,
+			warnings: []ParseWarning{
+				{Field: "configuration", Message: "Unexpected bool value"},
+				{Field: "configuration.directives.opcache.optimization_level", Message: "Directive not found"},
+				{Field: "configuration.directives.opcache.memory_consumption", Message: "Directive not found"},
+				{Field: "configuration.directives.opcache.max_wasted_percentage", Message: "Directive not found"},
+				{Field: "configuration.directives.opcache.interned_strings_buffer", Message: "Directive not found"},
+				{Field: "configuration.directives.opcache.max_accelerated_files", Message: "Directive not found"},
+			},
+		},
+		{
+			fixture:         "php74-disabled.json",
+			state:           NodeStateDisabled,
+			phpVersion:      "7.4.33",
+			protocolVersion: LegacyAgentProtocolVersion,
+		},
+		{
+			fixture:         "php74-without-scripts.json",
+			state:           NodeStateEnabled,
+			phpVersion:      "7.4.33",
+			protocolVersion: LegacyAgentProtocolVersion,
+			apcuEnabled:     true,
+		},
+		{
+			fixture:         "php80-jit.json",
+			state:           NodeStateEnabled,
+			phpVersion:      "8.0.30",
+			scriptsCount:    3,
+			protocolVersion: AgentProtocolVersion,
+			jit:             true,
+		},
+		{
+			fixture:         "php81-null-status.json",
+			state:           NodeStateEmpty,
+			phpVersion:      "8.1.27",
+			protocolVersion: AgentProtocolVersion,
+			warnings: []ParseWarning{
+				{Field: "status", Message: "Unexpected null value"},
+			},
+		},
+		{
+			fixture:         "php82-preload.json",
+			state:           NodeStateDegraded,
+			phpVersion:      "8.2.24",
+			scriptsCount:    3,
+			protocolVersion: AgentProtocolVersion,
+			jit:             true,
+			preload:         true,
+			apcuEnabled:     true,
+			apcuCacheInfo:   true,
+		},
+		{
+			fixture:         "php83-after-reset.json",
+			state:           NodeStateEmpty,
+			phpVersion:      "8.3.12",
+			protocolVersion: AgentProtocolVersion,
+			jit:             true,
+			warnings: []ParseWarning{
+				{Field: "configuration.directives.opcache.optimization_level", Message: "Expected number but got 0x7FFEBFFF"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.fixture, func(t *testing.T) {
+			response, err := os.ReadFile(filepath.Join("testdata", testCase.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nodeStatistics, err := AgentMessageParser{}.ParseReader(bytes.NewReader(response))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			opcacheStatistics := nodeStatistics.OpcacheStatistics
+
+			if opcacheStatistics.State != testCase.state {
+				t.Errorf("state: got %s, want %s", opcacheStatistics.State, testCase.state)
+			}
+
+			if opcacheStatistics.PHPVersion != testCase.phpVersion {
+				t.Errorf("PHP version: got %q, want %q", opcacheStatistics.PHPVersion, testCase.phpVersion)
+			}
+
+			if len(opcacheStatistics.Scripts) != testCase.scriptsCount {
+				t.Errorf("scripts: got %d, want %d", len(opcacheStatistics.Scripts), testCase.scriptsCount)
+			}
+
+			if nodeStatistics.Agent.ProtocolVersion != testCase.protocolVersion {
+				t.Errorf("protocol version: got %d, want %d", nodeStatistics.Agent.ProtocolVersion, testCase.protocolVersion)
+			}
+
+			if (opcacheStatistics.Jit != nil) != testCase.jit {
+				t.Errorf("JIT status: got %v, want present %v", opcacheStatistics.Jit, testCase.jit)
+			}
+
+			if (opcacheStatistics.Preload != nil) != testCase.preload {
+				t.Errorf("preload status: got %v, want present %v", opcacheStatistics.Preload, testCase.preload)
+			}
+
+			if nodeStatistics.ApcuStatistics.Enabled != testCase.apcuEnabled {
+				t.Errorf("APCu enabled: got %v, want %v", nodeStatistics.ApcuStatistics.Enabled, testCase.apcuEnabled)
+			}
+
+			if (nodeStatistics.ApcuStatistics.CacheInfo != nil) != testCase.apcuCacheInfo {
+				t.Errorf("APCu cache info: got %v, want present %v", nodeStatistics.ApcuStatistics.CacheInfo, testCase.apcuCacheInfo)
+			}
+
+			if len(opcacheStatistics.ParseWarnings) != 0 || len(testCase.warnings) != 0 {
+				if !reflect.DeepEqual(opcacheStatistics.ParseWarnings, testCase.warnings) {
+					t.Errorf("parse warnings: got %v, want %v", opcacheStatistics.ParseWarnings, testCase.warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestParseReaderErrors(t *testing.T) {
+	testCases := map[string]string{
+		"empty response":        ``,
+		"not an object":         `["status"]`,
+		"truncated message":     `{"configuration":{"directives":{"opcache.enable":true`,
+		"protocol not first":    `{"status":false,"protocol":{"version":2}}`,
+		"truncated script list": `{"protocol":{"version":2},"status":{"opcache_enabled":true,"scripts":[{"full_path":"/index.php"`,
+	}
+
+	for name, response := range testCases {
+		t.Run(name, func(t *testing.T) {
+			nodeStatistics, err := AgentMessageParser{}.ParseReader(bytes.NewReader([]byte(response)))
+			if err == nil {
+				t.Fatalf("expected error, got %+v", nodeStatistics)
+			}
+
+			var parseError *ParseError
+			if !errors.As(err, &parseError) {
+				t.Errorf("expected ParseError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func FuzzParseReader(f *testing.F) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		response, err := os.ReadFile(fixture)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(response)
+	}
+
+	f.Add(buildAgentResponse(3))
+
+	f.Fuzz(func(t *testing.T, response []byte) {
+		nodeStatistics, err := AgentMessageParser{}.ParseReader(bytes.NewReader(response))
+		if err == nil && nodeStatistics == nil {
+			t.Fatal("no statistics returned without error")
+		}
+	})
+}
+
+// buildAgentResponse builds response of legacy agent with passed count of cached scripts
+func buildAgentResponse(scriptsCount int) []byte {
+	buffer := bytes.Buffer{}
+
+	buffer.WriteString(`{"configuration":{"directives":{"opcache.enable":true,"opcache.max_accelerated_files":1000000},` +
+		`"version":{"version":"8.2.0"}},"status":{"opcache_enabled":true,"cache_full":false,` +
+		`"opcache_statistics":{"num_cached_scripts":` + fmt.Sprint(scriptsCount) + `,"max_cached_keys":1048793},` +
+		`"memory_usage":{"used_memory":1000,"free_memory":1000,"wasted_memory":0},` +
+		`"interned_strings_usage":{"buffer_size":8,"used_memory":4,"free_memory":4,"number_of_strings":1},` +
+		`"scripts":{`)
+
+	for i := 0; i < scriptsCount; i++ {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		path := fmt.Sprintf("/var/www/project/vendor/package%d/src/Class%d.php", i%100, i)
+		fmt.Fprintf(
+			&buffer,
+			`%q:{"full_path":%q,"hits":%d,"memory_consumption":%d,"last_used":"Mon Oct 19 10:00:00 2026",`+
+				`"last_used_timestamp":1792400000,"timestamp":1792300000}`,
+			path,
+			path,
+			i,
+			i*10,
+		)
+	}
+
+	buffer.WriteString(`}},"apcu":{"enabled":false}}`)
+
+	return buffer.Bytes()
+}
+
+// legacyAgentMessage is agent message as decoded before streaming parser, with whole script list in memory
+type legacyAgentMessage struct {
+	Configuration struct {
+		Directives map[string]interface{} `json:"directives"`
+	} `json:"configuration"`
+	Status struct {
+		Scripts map[string]struct {
+			FullPath          string `json:"full_path"`
+			Hits              int    `json:"hits"`
+			CreateTimestamp   int64  `json:"timestamp"`
+			LastUsedTimestamp int64  `json:"last_used_timestamp"`
+			Memory            int    `json:"memory_consumption"`
+		} `json:"scripts"`
+	} `json:"status"`
+}
+
+func BenchmarkParseReader(b *testing.B) {
+	response := buildAgentResponse(100000)
+
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(response)))
+
+		for i := 0; i < b.N; i++ {
+			nodeStatistics, err := AgentMessageParser{}.ParseReader(bytes.NewReader(response))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			if len(nodeStatistics.OpcacheStatistics.Scripts) != 100000 {
+				b.Fatalf("Unexpected count of scripts %d", len(nodeStatistics.OpcacheStatistics.Scripts))
+			}
+		}
+	})
+
+	b.Run("unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(response)))
+
+		for i := 0; i < b.N; i++ {
+			// whole body read to memory before decoding
+			body, err := io.ReadAll(bytes.NewReader(response))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			message := legacyAgentMessage{}
+			if err := json.Unmarshal(body, &message); err != nil {
+				b.Fatal(err)
+			}
+
+			scripts := make(map[string]Script, len(message.Status.Scripts))
+			for path, script := range message.Status.Scripts {
+				scripts[path] = Script{
+					Hits:              script.Hits,
+					CreateTimestamp:   script.CreateTimestamp,
+					LastUsedTimestamp: script.LastUsedTimestamp,
+					Memory:            script.Memory,
+				}
+			}
+		}
+	})
+}
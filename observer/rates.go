@@ -0,0 +1,94 @@
+package observer
+
+import (
+	"time"
+)
+
+// rateSample holds lifetime counters of node taken on pull
+type rateSample struct {
+	pulledAt        time.Time
+	startTime       int64
+	lastRestartTime int64
+	hits            int
+	misses          int
+	oomRestarts     int
+	hashRestarts    int
+	manualRestarts  int
+}
+
+func newRateSample(status NodeOpcacheStatus, pulledAt time.Time) rateSample {
+	return rateSample{
+		pulledAt:        pulledAt,
+		startTime:       status.StartTime,
+		lastRestartTime: status.Restarts.LastRestartTime,
+		hits:            status.KeyHits.Hits,
+		misses:          status.KeyHits.Misses,
+		oomRestarts:     status.Restarts.OutOfMemoryCount,
+		hashRestarts:    status.Restarts.HashCount,
+		manualRestarts:  status.Restarts.ManualCount,
+	}
+}
+
+// calculateRates derives interval rates from two samples of node counters.
+// When opcache started again (start time changed) all counters begin from zero,
+// when opcache restarted (last restart time changed) hits and misses begin from zero.
+func calculateRates(previous rateSample, current rateSample) *Rates {
+	intervalStart := previous.pulledAt
+	countersReset := false
+
+	hitsDelta := current.hits - previous.hits
+	missesDelta := current.misses - previous.misses
+	oomRestartsDelta := current.oomRestarts - previous.oomRestarts
+	hashRestartsDelta := current.hashRestarts - previous.hashRestarts
+	manualRestartsDelta := current.manualRestarts - previous.manualRestarts
+
+	if current.startTime != previous.startTime {
+		// opcache started again, so all counters accumulated from start
+		countersReset = true
+		hitsDelta = current.hits
+		missesDelta = current.misses
+		oomRestartsDelta = current.oomRestarts
+		hashRestartsDelta = current.hashRestarts
+		manualRestartsDelta = current.manualRestarts
+
+		if startedAt := time.Unix(current.startTime, 0); startedAt.After(intervalStart) {
+			intervalStart = startedAt
+		}
+	} else if current.lastRestartTime != previous.lastRestartTime || hitsDelta < 0 || missesDelta < 0 {
+		// opcache restarted, so hits and misses accumulated from restart
+		countersReset = true
+		hitsDelta = current.hits
+		missesDelta = current.misses
+
+		if restartedAt := time.Unix(current.lastRestartTime, 0); restartedAt.After(intervalStart) {
+			intervalStart = restartedAt
+		}
+	}
+
+	rates := &Rates{
+		IntervalSeconds:        current.pulledAt.Sub(intervalStart).Seconds(),
+		NewOutOfMemoryRestarts: nonNegative(oomRestartsDelta),
+		NewHashRestarts:        nonNegative(hashRestartsDelta),
+		NewManualRestarts:      nonNegative(manualRestartsDelta),
+		CountersReset:          countersReset,
+	}
+
+	if rates.IntervalSeconds > 0 {
+		rates.HitsPerSecond = float64(nonNegative(hitsDelta)) / rates.IntervalSeconds
+		rates.MissesPerSecond = float64(nonNegative(missesDelta)) / rates.IntervalSeconds
+	}
+
+	if requests := nonNegative(hitsDelta) + nonNegative(missesDelta); requests > 0 {
+		rates.HitRatio = float64(nonNegative(hitsDelta)) / float64(requests)
+	}
+
+	return rates
+}
+
+func nonNegative(value int) int {
+	if value < 0 {
+		return 0
+	}
+
+	return value
+}
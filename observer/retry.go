@@ -0,0 +1,97 @@
+package observer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+// agentStatusError returned when agent responded with non successful status
+type agentStatusError struct {
+	statusCode int
+	status     string
+}
+
+func (e *agentStatusError) Error() string {
+	return fmt.Sprintf("Observable node return error %s", e.status)
+}
+
+// fetchNodeStatisticsWithRetry retries failed pull with exponential backoff and jitter.
+// Retries stop when pulling stopped or when time left until retry deadline is shorter
+// than delay before next retry.
+func (o *Observer) fetchNodeStatisticsWithRetry(
+	groupConfig configuration.GroupConfig,
+	host string,
+	includeScripts bool,
+	allowRetries bool,
+	retryDeadline time.Time,
+) (*NodeStatistics, error) {
+	attempts := 0
+	if allowRetries {
+		attempts = o.Retry.Attempts
+	}
+
+	for attempt := 0; ; attempt++ {
+		nodeStatistics, err := o.fetchNodeStatistics(groupConfig, host, includeScripts)
+		if err == nil || attempt >= attempts || !isRetryableError(err) {
+			return nodeStatistics, err
+		}
+
+		delay := o.retryDelay(attempt)
+		retryTime := time.Now().Add(delay)
+
+		if time.Until(retryDeadline) < delay {
+			log.Printf("Pull of node %s failed, no time left to retry: %v", host, err)
+			return nodeStatistics, err
+		}
+
+		log.Printf("Pull of node %s failed, retry in %s: %v", host, delay, err)
+
+		if !waitUntil(retryTime, o.stopPulling) {
+			return nodeStatistics, err
+		}
+	}
+}
+
+// retryDelay doubles delay on every attempt and randomizes it within upper half,
+// so retries of nodes failed at same time are spread
+func (o *Observer) retryDelay(attempt int) time.Duration {
+	delay := time.Duration(o.Retry.InitialDelaySeconds) * time.Second
+	maxDelay := time.Duration(o.Retry.MaxDelaySeconds) * time.Second
+
+	for i := 0; i < attempt && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isRetryableError tells whether failure may be transient. Invalid or too large
+// responses and client errors will be same on retry.
+func isRetryableError(err error) bool {
+	var statusError *agentStatusError
+	if errors.As(err, &statusError) {
+		return statusError.statusCode >= http.StatusInternalServerError ||
+			statusError.statusCode == http.StatusTooManyRequests
+	}
+
+	var parseError *ParseError
+	if errors.As(err, &parseError) || errors.Is(err, ErrResponseTooLarge) || errors.Is(err, ErrFastCGINotConfigured) {
+		return false
+	}
+
+	return true
+}
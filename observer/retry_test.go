@@ -0,0 +1,93 @@
+package observer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+func TestFetchNodeStatisticsWithRetry(t *testing.T) {
+	testCases := []struct {
+		name            string
+		retryDeadline   time.Duration
+		stopped         bool
+		successOnRetry  bool
+		expectedFetches int
+	}{
+		{name: "retried until success", retryDeadline: 5 * time.Second, successOnRetry: true, expectedFetches: 2},
+		{name: "retry after deadline skipped", retryDeadline: 100 * time.Millisecond, expectedFetches: 1},
+		{name: "retry without deadline skipped", expectedFetches: 1},
+		{name: "retry interrupted by stop", retryDeadline: 5 * time.Second, stopped: true, expectedFetches: 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			o, agentTransport := newTestObserver(t, "web1")
+			o.Retry = configuration.RetryConfig{Attempts: 3, InitialDelaySeconds: 1, MaxDelaySeconds: 1}
+			agentTransport.SetStatusResponse("web1", http.StatusServiceUnavailable, "")
+
+			o.stopPulling = make(chan struct{})
+			if testCase.stopped {
+				close(o.stopPulling)
+			}
+
+			if testCase.successOnRetry {
+				// agent recovers while first retry awaited
+				time.AfterFunc(100*time.Millisecond, func() {
+					agentTransport.SetStatusResponse("web1", http.StatusOK, readFixture(t, "php80-jit.json"))
+				})
+			}
+
+			retryDeadline := time.Now().Add(testCase.retryDeadline)
+
+			startedAt := time.Now()
+			groupConfig := o.Clusters[testClusterName].Groups[testGroupName]
+			_, err := o.fetchNodeStatisticsWithRetry(groupConfig, "web1", false, true, retryDeadline)
+
+			if testCase.successOnRetry != (err == nil) {
+				t.Errorf("unexpected result of pull: %v", err)
+			}
+
+			if fetches := len(nodeRequests(agentTransport, "web1", "")); fetches != testCase.expectedFetches {
+				t.Errorf("expected %d fetches, got %d", testCase.expectedFetches, fetches)
+			}
+
+			if !testCase.successOnRetry && time.Since(startedAt) > 200*time.Millisecond {
+				t.Errorf("failed pull took %s instead of returning without retry", time.Since(startedAt))
+			}
+		})
+	}
+}
+
+func TestUnscheduledPullRetryDeadline(t *testing.T) {
+	o, _ := newTestObserver(t, "web1")
+
+	testCases := []struct {
+		name                string
+		defaultPullInterval time.Duration
+		groupPullInterval   int64
+		expectedInterval    time.Duration
+	}{
+		{"default interval of configuration before pulling started", 0, 0, configuration.DefaultRefreshIntervalSeconds * time.Second},
+		{"default interval", time.Minute, 0, time.Minute},
+		{"interval of group", time.Minute, 5, 5 * time.Second},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			o.defaultPullInterval = testCase.defaultPullInterval
+			groupConfig := o.Clusters[testClusterName].Groups[testGroupName]
+			groupConfig.PullIntervalSeconds = testCase.groupPullInterval
+			o.Clusters[testClusterName].Groups[testGroupName] = groupConfig
+
+			startedAt := time.Now()
+			deadline := o.unscheduledPullRetryDeadline(testClusterName, testGroupName)
+
+			if interval := deadline.Sub(startedAt); interval < testCase.expectedInterval || interval > testCase.expectedInterval+time.Second {
+				t.Errorf("got deadline in %s, want %s", interval, testCase.expectedInterval)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package observer
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/GoMetric/opcache-dashboard/configuration"
+)
+
+// groupPullInterval returns interval of group, falling back to interval of cluster and then to default one
+func groupPullInterval(
+	clusterConfig configuration.ClusterConfig,
+	groupConfig configuration.GroupConfig,
+	defaultInterval time.Duration,
+) time.Duration {
+	if groupConfig.PullIntervalSeconds > 0 {
+		return time.Duration(groupConfig.PullIntervalSeconds) * time.Second
+	}
+
+	if clusterConfig.PullIntervalSeconds > 0 {
+		return time.Duration(clusterConfig.PullIntervalSeconds) * time.Second
+	}
+
+	return defaultInterval
+}
+
+// pullIntervalOf returns interval of scheduled pulls of group, default interval
+// of configuration used when pulling not started
+func (o *Observer) pullIntervalOf(clusterName string, groupName string) time.Duration {
+	defaultInterval := o.defaultPullInterval
+	if defaultInterval <= 0 {
+		defaultInterval = configuration.DefaultRefreshIntervalSeconds * time.Second
+	}
+
+	clusterConfig := o.Clusters[clusterName]
+
+	return groupPullInterval(clusterConfig, clusterConfig.Groups[groupName], defaultInterval)
+}
+
+// unscheduledPullRetryDeadline bounds retries of pulls made outside of schedule,
+// like manual refresh, by pull interval of group: by then scheduled pull fetches node anyway
+func (o *Observer) unscheduledPullRetryDeadline(clusterName string, groupName string) time.Time {
+	return time.Now().Add(o.pullIntervalOf(clusterName, groupName))
+}
+
+// pullGroupOnSchedule pulls nodes of group every pull interval. Every node has own slot
+// in interval and pulled at random moment of first half of slot, so nodes of shared
+// infrastructure are not hit at same instant.
+func (o *Observer) pullGroupOnSchedule(
+	stop <-chan struct{},
+	clusterName string,
+	groupName string,
+	groupConfig configuration.GroupConfig,
+	interval time.Duration,
+	includeScripts bool,
+) {
+	if len(groupConfig.Hosts) == 0 || interval <= 0 {
+		return
+	}
+
+	slot := interval / time.Duration(len(groupConfig.Hosts))
+
+	// first cycle follows initial pull of all agents, random phase keeps groups with same interval apart
+	cycleStart := time.Now().Add(interval + randomDuration(slot))
+
+	for {
+		for i, host := range groupConfig.Hosts {
+			pullTime := cycleStart.Add(time.Duration(i)*slot + randomDuration(slot/2))
+			if !waitUntil(pullTime, stop) {
+				return
+			}
+
+			// retries of failed pull must not delay pulls of next nodes
+			retryDeadline := cycleStart.Add(time.Duration(i+1) * slot)
+
+			o.pullAgent(groupConfig, clusterName, groupName, host, includeScripts, retryDeadline)
+		}
+
+		o.notifyPullListeners()
+
+		// when pulls took longer than interval, next cycle starts immediately
+		cycleStart = cycleStart.Add(interval)
+		if now := time.Now(); cycleStart.Before(now) {
+			cycleStart = now
+		}
+	}
+}
+
+// waitUntil returns false if pulling stopped before passed time
+func waitUntil(wakeUpTime time.Time, stop <-chan struct{}) bool {
+	timer := time.NewTimer(time.Until(wakeUpTime))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+func randomDuration(maxDuration time.Duration) time.Duration {
+	if maxDuration <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(maxDuration)))
+}
@@ -0,0 +1,47 @@
+package observer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPullGroupOnSchedule(t *testing.T) {
+	o, agentTransport := newTestObserver(t, "web1", "web2")
+	agentTransport.SetStatusResponse("web1", http.StatusOK, readFixture(t, "php80-jit.json"))
+	agentTransport.SetStatusResponse("web2", http.StatusOK, readFixture(t, "php80-jit.json"))
+
+	pulled := waitForPull(o)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	groupConfig := o.Clusters[testClusterName].Groups[testGroupName]
+
+	go func() {
+		defer close(stopped)
+		o.pullGroupOnSchedule(stop, testClusterName, testGroupName, groupConfig, 50*time.Millisecond, true)
+	}()
+
+	receivePull(t, pulled)
+	close(stop)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("schedule not stopped")
+	}
+
+	for _, host := range []string{"web1", "web2"} {
+		requests := nodeRequests(agentTransport, host, "")
+		if len(requests) == 0 || !requests[0].IncludeScripts {
+			t.Errorf("%s: expected status requests with scripts, got %+v", host, requests)
+		}
+	}
+
+	// no pulls after stop
+	requestsCount := len(agentTransport.GetRequests())
+	time.Sleep(150 * time.Millisecond)
+
+	if len(agentTransport.GetRequests()) != requestsCount {
+		t.Error("nodes pulled after schedule stopped")
+	}
+}
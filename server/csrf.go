@@ -0,0 +1,88 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// CsrfCookieName is a name of cookie which holds CSRF token issued to UI
+const CsrfCookieName = "csrf_token"
+
+// CsrfHeaderName is a name of header which must repeat value of CSRF cookie
+const CsrfHeaderName = "X-CSRF-Token"
+
+// CsrfProtection implements double submit cookie protection of mutating requests.
+// Requests with valid bearer token are accepted without CSRF token.
+type CsrfProtection struct {
+	apiToken string
+}
+
+func NewCsrfProtection(apiToken string) *CsrfProtection {
+	return &CsrfProtection{
+		apiToken: apiToken,
+	}
+}
+
+// IssueToken sets CSRF cookie if client still has no one
+func (p *CsrfProtection) IssueToken(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(CsrfCookieName); err == nil && cookie.Value != "" {
+		return
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CsrfCookieName,
+		Value:    hex.EncodeToString(tokenBytes),
+		Path:     "/",
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Middleware rejects requests without valid CSRF or bearer token
+func (p *CsrfProtection) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.isBearerAuthorized(r) || p.isCsrfTokenValid(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+	})
+}
+
+func (p *CsrfProtection) isBearerAuthorized(r *http.Request) bool {
+	if p.apiToken == "" {
+		return false
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimPrefix(authorization, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(p.apiToken)) == 1
+}
+
+func (p *CsrfProtection) isCsrfTokenValid(r *http.Request) bool {
+	cookie, err := r.Cookie(CsrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	headerToken := r.Header.Get(CsrfHeaderName)
+	if headerToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(headerToken), []byte(cookie.Value)) == 1
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCsrfProtectionMiddleware(t *testing.T) {
+	const cookieToken = "0123456789abcdef"
+
+	testCases := []struct {
+		name          string
+		apiToken      string
+		cookie        string
+		header        string
+		authorization string
+		statusCode    int
+	}{
+		{name: "matching cookie and header", cookie: cookieToken, header: cookieToken, statusCode: http.StatusOK},
+		{name: "header differs from cookie", cookie: cookieToken, header: "fedcba9876543210", statusCode: http.StatusForbidden},
+		{name: "header is prefix of cookie", cookie: cookieToken, header: cookieToken[:8], statusCode: http.StatusForbidden},
+		{name: "missing header", cookie: cookieToken, statusCode: http.StatusForbidden},
+		{name: "missing cookie", header: cookieToken, statusCode: http.StatusForbidden},
+		{name: "empty cookie and header", statusCode: http.StatusForbidden},
+		{name: "valid bearer token", apiToken: "secret", authorization: "Bearer secret", statusCode: http.StatusOK},
+		{name: "invalid bearer token", apiToken: "secret", authorization: "Bearer wrong", statusCode: http.StatusForbidden},
+		{name: "bearer token without scheme", apiToken: "secret", authorization: "secret", statusCode: http.StatusForbidden},
+		{name: "bearer token when not configured", authorization: "Bearer ", statusCode: http.StatusForbidden},
+		{
+			name:          "invalid bearer token with valid CSRF token",
+			apiToken:      "secret",
+			authorization: "Bearer wrong",
+			cookie:        cookieToken,
+			header:        cookieToken,
+			statusCode:    http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewCsrfProtection(testCase.apiToken).Middleware(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			)
+
+			request := httptest.NewRequest(http.MethodPost, "/api/nodes/statistics/refresh", nil)
+			if testCase.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: CsrfCookieName, Value: testCase.cookie})
+			}
+
+			if testCase.header != "" {
+				request.Header.Set(CsrfHeaderName, testCase.header)
+			}
+
+			if testCase.authorization != "" {
+				request.Header.Set("Authorization", testCase.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.statusCode {
+				t.Errorf("status: got %d, want %d", recorder.Code, testCase.statusCode)
+			}
+		})
+	}
+}
+
+func TestCsrfProtectionIssueToken(t *testing.T) {
+	csrfProtection := NewCsrfProtection("")
+
+	recorder := httptest.NewRecorder()
+	csrfProtection.IssueToken(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CsrfCookieName || len(cookies[0].Value) != 64 {
+		t.Fatalf("expected issued CSRF cookie, got %v", cookies)
+	}
+
+	if cookies[0].SameSite != http.SameSiteStrictMode || cookies[0].Secure {
+		t.Errorf("unexpected cookie attributes: %+v", cookies[0])
+	}
+
+	// issued token kept, so tokens of open tabs stay valid
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(cookies[0])
+
+	recorder = httptest.NewRecorder()
+	csrfProtection.IssueToken(recorder, request)
+
+	if reissued := recorder.Result().Cookies(); len(reissued) != 0 {
+		t.Errorf("token reissued: %v", reissued)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventStreamKeepaliveInterval defines how often comment sent to idle event stream to keep connection open
+const EventStreamKeepaliveInterval = 15 * time.Second
+
+// Count of last events kept for resuming of stream by Last-Event-ID
+const eventHistorySize = 1000
+
+// Count of events buffered for slow subscriber before it disconnected
+const subscriberBufferSize = 256
+
+// StreamEvent is single event of Server-Sent Events stream
+type StreamEvent struct {
+	ID   uint64
+	Type string
+	Data interface{}
+}
+
+// EventBroker delivers published events to subscribers of Server-Sent Events stream
+type EventBroker struct {
+	mutex       sync.Mutex
+	lastEventID uint64
+	history     []StreamEvent
+	subscribers map[chan StreamEvent]bool
+	// closed on shutdown of server to end open streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		history:     []StreamEvent{},
+		subscribers: map[chan StreamEvent]bool{},
+		done:        make(chan struct{}),
+	}
+}
+
+// Close ends all open streams. Shutdown of server does not cancel contexts of active requests,
+// so without it server waits for streams until shutdown timeout.
+func (b *EventBroker) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// Publish sends event to all subscribers. Never blocks: subscriber which can not accept
+// event is disconnected and may resume stream from last received event.
+func (b *EventBroker) Publish(eventType string, data interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastEventID++
+
+	event := StreamEvent{
+		ID:   b.lastEventID,
+		Type: eventType,
+		Data: data,
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistorySize {
+		b.history = b.history[len(b.history)-eventHistorySize:]
+	}
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// subscribe registers subscriber and returns events missed after passed event id.
+// Second returned value is false when missed events are not in history anymore.
+func (b *EventBroker) subscribe(lastEventID uint64, resume bool) (chan StreamEvent, []StreamEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscriber := make(chan StreamEvent, subscriberBufferSize)
+	b.subscribers[subscriber] = true
+
+	if !resume || lastEventID == b.lastEventID {
+		return subscriber, nil, true
+	}
+
+	// id never issued by this process, e.g. client connected before restart of dashboard
+	if lastEventID > b.lastEventID {
+		return subscriber, nil, false
+	}
+
+	if len(b.history) == 0 || b.history[0].ID > lastEventID+1 {
+		return subscriber, nil, false
+	}
+
+	missedEvents := []StreamEvent{}
+	for _, event := range b.history {
+		if event.ID > lastEventID {
+			missedEvents = append(missedEvents, event)
+		}
+	}
+
+	return subscriber, missedEvents, true
+}
+
+func (b *EventBroker) unsubscribe(subscriber chan StreamEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.subscribers[subscriber]; ok {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// ServeHTTP streams events to client, resuming from "Last-Event-ID" header or "lastEventId" query parameter
+func (b *EventBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	responseController := http.NewResponseController(w)
+
+	// stream lives longer than write timeout of server
+	responseController.SetWriteDeadline(time.Time{})
+
+	lastEventIDHeader := r.Header.Get("Last-Event-ID")
+	if lastEventIDHeader == "" {
+		lastEventIDHeader = r.URL.Query().Get("lastEventId")
+	}
+
+	lastEventID, err := strconv.ParseUint(lastEventIDHeader, 10, 64)
+	resume := err == nil
+
+	subscriber, missedEvents, isResumed := b.subscribe(lastEventID, resume)
+	defer b.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// client must refetch full state when missed events are lost
+	if !isResumed {
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+
+	for _, event := range missedEvents {
+		if err := writeStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+
+	if err := responseController.Flush(); err != nil {
+		return
+	}
+
+	keepaliveTicker := time.NewTicker(EventStreamKeepaliveInterval)
+	defer keepaliveTicker.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscriber:
+			if !ok {
+				// subscriber was too slow and disconnected, client will reconnect with Last-Event-ID
+				return
+			}
+
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-keepaliveTicker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-b.done:
+			return
+		}
+
+		if err := responseController.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event StreamEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Printf("Can not encode event %s: %v", event.Type, err)
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+
+	return err
+}
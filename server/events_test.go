@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receivedStreamEvent is single event read from stream, id empty for resync
+type receivedStreamEvent struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// openEventStream connects to broker and returns reader of stream
+func openEventStream(t *testing.T, serverURL string, lastEventID string) *bufio.Reader {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, serverURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { response.Body.Close() })
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("got content type %s", contentType)
+	}
+
+	return bufio.NewReader(response.Body)
+}
+
+// readStreamEvents reads passed count of events, skipping keepalive comments
+func readStreamEvents(t *testing.T, reader *bufio.Reader, count int) []receivedStreamEvent {
+	t.Helper()
+
+	events := []receivedStreamEvent{}
+	event := receivedStreamEvent{}
+
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("can not read event %d: %v", len(events)+1, err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if event.Type != "" {
+				events = append(events, event)
+			}
+
+			event = receivedStreamEvent{}
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	return events
+}
+
+// startEventBroker serves broker, open streams closed by broker before server waits for them
+func startEventBroker(t *testing.T) (*EventBroker, string) {
+	broker := NewEventBroker()
+	testServer := httptest.NewServer(broker)
+
+	t.Cleanup(func() {
+		broker.Close()
+		testServer.Close()
+	})
+
+	return broker, testServer.URL
+}
+
+func publishEvents(broker *EventBroker, count int) {
+	for i := 0; i < count; i++ {
+		broker.Publish("pullCompleted", map[string]int{"number": i + 1})
+	}
+}
+
+func TestEventBrokerLiveEvents(t *testing.T) {
+	broker, serverURL := startEventBroker(t)
+
+	stream := openEventStream(t, serverURL, "")
+	publishEvents(broker, 2)
+
+	expected := []receivedStreamEvent{
+		{ID: "1", Type: "pullCompleted", Data: `{"number":1}`},
+		{ID: "2", Type: "pullCompleted", Data: `{"number":2}`},
+	}
+
+	if events := readStreamEvents(t, stream, 2); !reflect.DeepEqual(events, expected) {
+		t.Errorf("got %+v", events)
+	}
+}
+
+func TestEventBrokerResume(t *testing.T) {
+	testCases := []struct {
+		name           string
+		publishedCount int
+		lastEventID    string
+		expectedFirst  receivedStreamEvent // first event received after reconnect
+	}{
+		{
+			name:           "missed events replayed",
+			publishedCount: 3,
+			lastEventID:    "1",
+			expectedFirst:  receivedStreamEvent{ID: "2", Type: "pullCompleted", Data: `{"number":2}`},
+		},
+		{
+			name:           "missed events replayed after history wrapped",
+			publishedCount: eventHistorySize + 10,
+			lastEventID:    "10",
+			expectedFirst:  receivedStreamEvent{ID: "11", Type: "pullCompleted", Data: `{"number":11}`},
+		},
+		{
+			name:           "missed events dropped from history",
+			publishedCount: eventHistorySize + 10,
+			lastEventID:    "9",
+			expectedFirst:  receivedStreamEvent{Type: "resync", Data: "{}"},
+		},
+		{
+			name:           "id issued before restart of dashboard",
+			publishedCount: 3,
+			lastEventID:    "50",
+			expectedFirst:  receivedStreamEvent{Type: "resync", Data: "{}"},
+		},
+		{
+			name:           "no missed events",
+			publishedCount: 3,
+			lastEventID:    "3",
+			expectedFirst:  receivedStreamEvent{ID: "4", Type: "pullCompleted", Data: `{"number":4}`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			broker, serverURL := startEventBroker(t)
+
+			publishEvents(broker, testCase.publishedCount)
+
+			stream := openEventStream(t, serverURL, testCase.lastEventID)
+
+			// live event follows replayed ones
+			broker.Publish("pullCompleted", map[string]int{"number": testCase.publishedCount + 1})
+
+			first := readStreamEvents(t, stream, 1)[0]
+			if first != testCase.expectedFirst {
+				t.Fatalf("got first event %+v, want %+v", first, testCase.expectedFirst)
+			}
+
+			// stream continues without gaps up to live event
+			expectedID := testCase.publishedCount + 1
+			if first.ID != "" {
+				for id := first.ID; id != itoa(expectedID); {
+					id = readStreamEvents(t, stream, 1)[0].ID
+				}
+			} else if live := readStreamEvents(t, stream, 1)[0]; live.ID != itoa(expectedID) {
+				t.Errorf("got live event %+v after resync", live)
+			}
+		})
+	}
+}
+
+func TestEventBrokerResumeByQueryParameter(t *testing.T) {
+	broker, serverURL := startEventBroker(t)
+
+	publishEvents(broker, 3)
+
+	stream := openEventStream(t, serverURL+"?lastEventId=2", "")
+
+	if event := readStreamEvents(t, stream, 1)[0]; event.ID != "3" {
+		t.Errorf("got %+v", event)
+	}
+}
+
+func TestEventBrokerClose(t *testing.T) {
+	broker, serverURL := startEventBroker(t)
+
+	stream := openEventStream(t, serverURL, "")
+
+	ended := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(stream)
+		ended <- err
+	}()
+
+	broker.Close()
+	// repeated close on shutdown of several servers is safe
+	broker.Close()
+
+	select {
+	case err := <-ended:
+		if err != nil {
+			t.Errorf("stream ended with error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not ended after close")
+	}
+}
+
+func itoa(number int) string {
+	return strconv.Itoa(number)
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WriteJSON writes body as JSON response, indented when "pretty=1" passed in query
+func WriteJSON(w http.ResponseWriter, r *http.Request, body interface{}) {
+	var jsonBody []byte
+
+	if r.URL.Query().Get("pretty") == "1" {
+		jsonBody, _ = json.MarshalIndent(body, "", "    ")
+	} else {
+		jsonBody, _ = json.Marshal(body)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonBody)
+}
+
+// WriteJSONError writes error message as JSON response with passed status code
+func WriteJSONError(w http.ResponseWriter, statusCode int, err error) {
+	jsonBody, _ := json.Marshal(map[string]string{
+		"error": err.Error(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(jsonBody)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// NewMethodNotAllowedHandler answers 405 with list of allowed methods
+func NewMethodNotAllowedHandler(allowedMethods ...string) http.Handler {
+	allow := strings.Join(allowedMethods, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		WriteJSONError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed, use "+allow))
+	})
+}
+
+// RejectOtherMethods registers handler answering 405 on paths of all routes of subrouter,
+// so requests with other methods do not fall through to routes registered later on router
+func RejectOtherMethods(router *mux.Router, subrouter *mux.Router, allowedMethods ...string) error {
+	methodNotAllowedHandler := NewMethodNotAllowedHandler(allowedMethods...)
+
+	return subrouter.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		router.Handle(pathTemplate, methodNotAllowedHandler)
+
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRejectOtherMethods(t *testing.T) {
+	router := mux.NewRouter()
+
+	mutatingRouter := router.Methods(http.MethodPost).Subrouter()
+	mutatingRouter.HandleFunc("/api/nodes/{hostName}/resetOpcache", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("reset " + mux.Vars(r)["hostName"]))
+	})
+
+	if err := RejectOtherMethods(router, mutatingRouter, http.MethodPost); err != nil {
+		t.Fatal(err)
+	}
+
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("index"))
+	})
+
+	testCases := []struct {
+		method     string
+		path       string
+		statusCode int
+		body       string
+	}{
+		{http.MethodPost, "/api/nodes/web1/resetOpcache", http.StatusOK, "reset web1"},
+		{http.MethodGet, "/api/nodes/web1/resetOpcache", http.StatusMethodNotAllowed, ""},
+		{http.MethodPut, "/api/nodes/web1/resetOpcache", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/clusters/production", http.StatusOK, "index"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.method+" "+testCase.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(testCase.method, testCase.path, nil))
+
+			if recorder.Code != testCase.statusCode {
+				t.Fatalf("status: got %d, want %d", recorder.Code, testCase.statusCode)
+			}
+
+			if testCase.statusCode == http.StatusMethodNotAllowed {
+				if allow := recorder.Header().Get("Allow"); allow != http.MethodPost {
+					t.Errorf("Allow header: got %q", allow)
+				}
+
+				return
+			}
+
+			if recorder.Body.String() != testCase.body {
+				t.Errorf("body: got %q, want %q", recorder.Body.String(), testCase.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits count of requests from single client with token bucket algorithm
+type RateLimiter struct {
+	mutex             sync.Mutex
+	buckets           map[string]*tokenBucket
+	requestsPerMinute int
+	burst             int
+	lastCleanup       time.Time
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+func NewRateLimiter(requestsPerMinute int, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		buckets:           map[string]*tokenBucket{},
+		requestsPerMinute: requestsPerMinute,
+		burst:             burst,
+		lastCleanup:       time.Now(),
+	}
+}
+
+// Allow checks if client may perform one more request
+func (l *RateLimiter) Allow(clientID string) bool {
+	if l.requestsPerMinute <= 0 {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+
+	// drop buckets of idle clients to keep map small
+	if now.Sub(l.lastCleanup) > time.Minute {
+		l.cleanup(now)
+	}
+
+	bucket, ok := l.buckets[clientID]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens:     float64(l.burst),
+			lastRefill: now,
+		}
+		l.buckets[clientID] = bucket
+	}
+
+	// refill tokens for elapsed time
+	bucket.tokens = l.refilledTokens(bucket, now)
+	bucket.lastRefill = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
+
+func (l *RateLimiter) refilledTokens(bucket *tokenBucket, now time.Time) float64 {
+	refillRate := float64(l.requestsPerMinute) / float64(time.Minute)
+	tokens := bucket.tokens + float64(now.Sub(bucket.lastRefill))*refillRate
+
+	if tokens > float64(l.burst) {
+		tokens = float64(l.burst)
+	}
+
+	return tokens
+}
+
+func (l *RateLimiter) cleanup(now time.Time) {
+	for clientID, bucket := range l.buckets {
+		if l.refilledTokens(bucket, now) >= float64(l.burst) {
+			delete(l.buckets, clientID)
+		}
+	}
+
+	l.lastCleanup = now
+}
+
+// Middleware rejects requests of clients which exceeded limit
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
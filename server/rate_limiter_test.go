@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// rewindBucket moves last refill of client bucket to the past, as if time elapsed
+func rewindBucket(l *RateLimiter, clientID string, elapsed time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.buckets[clientID].lastRefill = l.buckets[clientID].lastRefill.Add(-elapsed)
+}
+
+func allowedCount(l *RateLimiter, clientID string, requestsCount int) int {
+	allowed := 0
+	for i := 0; i < requestsCount; i++ {
+		if l.Allow(clientID) {
+			allowed++
+		}
+	}
+
+	return allowed
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	rateLimiter := NewRateLimiter(60, 3)
+
+	if allowed := allowedCount(rateLimiter, "10.0.0.1", 5); allowed != 3 {
+		t.Errorf("expected burst of 3 requests, got %d", allowed)
+	}
+
+	// clients limited separately
+	if !rateLimiter.Allow("10.0.0.2") {
+		t.Error("request of other client rejected")
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	testCases := []struct {
+		name     string
+		elapsed  time.Duration
+		expected int
+	}{
+		{name: "less than one token refilled", elapsed: 500 * time.Millisecond, expected: 0},
+		{name: "one token refilled", elapsed: time.Second, expected: 1},
+		{name: "two tokens refilled", elapsed: 2500 * time.Millisecond, expected: 2},
+		{name: "refill limited by burst", elapsed: time.Hour, expected: 3},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// one token per second
+			rateLimiter := NewRateLimiter(60, 3)
+			allowedCount(rateLimiter, "10.0.0.1", 3)
+
+			rewindBucket(rateLimiter, "10.0.0.1", testCase.elapsed)
+
+			if allowed := allowedCount(rateLimiter, "10.0.0.1", 5); allowed != testCase.expected {
+				t.Errorf("expected %d allowed requests, got %d", testCase.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestRateLimiterPartialRefillAccumulates(t *testing.T) {
+	rateLimiter := NewRateLimiter(60, 1)
+	rateLimiter.Allow("10.0.0.1")
+
+	// rejected request must not reset accumulated part of token
+	rewindBucket(rateLimiter, "10.0.0.1", 600*time.Millisecond)
+	if rateLimiter.Allow("10.0.0.1") {
+		t.Fatal("request allowed before token refilled")
+	}
+
+	rewindBucket(rateLimiter, "10.0.0.1", 600*time.Millisecond)
+	if !rateLimiter.Allow("10.0.0.1") {
+		t.Error("request rejected after token refilled")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	rateLimiter := NewRateLimiter(0, 1)
+
+	if allowed := allowedCount(rateLimiter, "10.0.0.1", 100); allowed != 100 {
+		t.Errorf("expected all requests allowed, got %d", allowed)
+	}
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	rateLimiter := NewRateLimiter(60, 2)
+	rateLimiter.Allow("10.0.0.1")
+	rateLimiter.Allow("10.0.0.2")
+	rateLimiter.Allow("10.0.0.2")
+
+	// first client refilled its bucket, second one still waits for tokens
+	rewindBucket(rateLimiter, "10.0.0.1", time.Minute)
+	rateLimiter.cleanup(time.Now())
+
+	if _, ok := rateLimiter.buckets["10.0.0.1"]; ok {
+		t.Error("bucket of idle client not removed")
+	}
+
+	if _, ok := rateLimiter.buckets["10.0.0.2"]; !ok {
+		t.Error("bucket of limited client removed")
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	handler := NewRateLimiter(60, 1).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	statusCodes := []int{}
+	for _, remoteAddr := range []string{"10.0.0.1:50000", "10.0.0.1:50001", "10.0.0.2:50000"} {
+		request := httptest.NewRequest(http.MethodPost, "/api/nodes/statistics/refresh", nil)
+		request.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		statusCodes = append(statusCodes, recorder.Code)
+
+		if recorder.Code == http.StatusTooManyRequests && recorder.Header().Get("Retry-After") == "" {
+			t.Error("Retry-After header not set")
+		}
+	}
+
+	// requests from other port of same host share bucket
+	expected := []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i := range expected {
+		if statusCodes[i] != expected[i] {
+			t.Errorf("request %d: got status %d, want %d", i, statusCodes[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CertificateReloadCheckInterval defines how often certificate files checked for changes
+const CertificateReloadCheckInterval = 10 * time.Second
+
+// CertificateReloader loads TLS certificate, key and optional client CA
+// and reloads them when files changed on disk without restart of server
+type CertificateReloader struct {
+	mutex        sync.RWMutex
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	certificate  *tls.Certificate
+	clientCAPool *x509.CertPool
+	modTimes     map[string]time.Time
+	stopChan     chan struct{}
+}
+
+func NewCertificateReloader(certFile string, keyFile string, clientCAFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		modTimes:     map[string]time.Time{},
+		stopChan:     make(chan struct{}),
+	}
+
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// TLSConfig builds server TLS configuration which always uses last loaded certificates.
+// Certificate provided by GetCertificate, so base configuration with its NextProtos
+// used for handshake and HTTP/2 stays enabled.
+func (r *CertificateReloader) TLSConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: r.getCertificate,
+	}
+
+	// client CA may be reloaded, so client authentication configured per handshake
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mutex.RLock()
+		clientCAPool := r.clientCAPool
+		r.mutex.RUnlock()
+
+		if clientCAPool == nil {
+			return nil, nil
+		}
+
+		clientConfig := config.Clone()
+		clientConfig.GetConfigForClient = nil
+		clientConfig.ClientCAs = clientCAPool
+		clientConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+		return clientConfig, nil
+	}
+
+	return config
+}
+
+func (r *CertificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.certificate, nil
+}
+
+// StartWatching periodically checks modification time of files and reloads them on change
+func (r *CertificateReloader) StartWatching(checkInterval time.Duration) {
+	ticker := time.NewTicker(checkInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if !r.isChanged() {
+					continue
+				}
+
+				if err := r.reload(); err != nil {
+					log.Printf("Can not reload TLS certificates, previous ones still used: %v", err)
+				} else {
+					log.Printf("TLS certificates reloaded")
+				}
+			case <-r.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopWatching stops checking of certificate files
+func (r *CertificateReloader) StopWatching() {
+	close(r.stopChan)
+}
+
+func (r *CertificateReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+
+	return files
+}
+
+func (r *CertificateReloader) isChanged() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, file := range r.files() {
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		if !fileInfo.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *CertificateReloader) reload() error {
+	modTimes := map[string]time.Time{}
+	for _, file := range r.files() {
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		modTimes[file] = fileInfo.ModTime()
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("Can not load TLS certificate: %v", err)
+	}
+
+	var clientCAPool *x509.CertPool
+	if r.clientCAFile != "" {
+		clientCAContent, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("Can not read client CA: %v", err)
+		}
+
+		clientCAPool = x509.NewCertPool()
+		if !clientCAPool.AppendCertsFromPEM(clientCAContent) {
+			return errors.New("Client CA file contains no valid certificates")
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.certificate = &certificate
+	r.clientCAPool = clientCAPool
+	r.modTimes = modTimes
+
+	return nil
+}
+
+// NewHTTPSRedirectHandler builds handler which redirects plain HTTP requests to HTTPS port
+func NewHTTPSRedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if splitHost, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = splitHost
+		}
+
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
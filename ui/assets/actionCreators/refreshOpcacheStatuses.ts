@@ -1,10 +1,11 @@
 import {opcacheStatusesRefreshed} from '/actions/opcacheStatusesActions';
 import fetchOpcacheStatuses from '/actionCreators/fetchOpcacheStatuses';
+import postCommand from '/dataProviders/postCommand';
 
 export default function() {
     return (dispatch, getState) => {
         // request for fetch new opcache status from nodes
-        fetch('/api/nodes/statistics/refresh')
+        postCommand('/api/nodes/statistics/refresh')
             .then(() => {
                 // wait before status updated and refresh state
                 setTimeout(() => {
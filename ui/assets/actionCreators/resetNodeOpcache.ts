@@ -1,9 +1,10 @@
 import refreshOpcacheStatuses from '/actionCreators/refreshOpcacheStatuses';
+import postCommand from '/dataProviders/postCommand';
 
 export default function(clusterName: string, groupName: string, host: string) {
     return (dispatch, getState) => {
         // request for fetch new opcache status from nodes
-        fetch('/api/nodes/' + clusterName + '/'  + groupName + '/' + host + '/resetOpcache')
+        postCommand('/api/nodes/' + clusterName + '/'  + groupName + '/' + host + '/resetOpcache')
             .then(() => {
                 // wait before status updated and refresh state
                 dispatch(refreshOpcacheStatuses());
@@ -0,0 +1,29 @@
+import {nodeStatusUpdated} from '/actions/opcacheStatusesActions';
+import fetchOpcacheStatuses from '/actionCreators/fetchOpcacheStatuses';
+import fetchApcuStatuses from '/actionCreators/fetchApcuStatuses';
+
+export default function() {
+    return (dispatch, getState) => {
+        // browser reconnects automatically and resumes stream by Last-Event-ID
+        const eventSource = new EventSource('/api/events');
+
+        eventSource.addEventListener('nodeUpdated', (event: MessageEvent) => {
+            const nodeEvent = JSON.parse(event.data);
+
+            dispatch(nodeStatusUpdated(
+                nodeEvent.ClusterName,
+                nodeEvent.GroupName,
+                nodeEvent.HostName,
+                nodeEvent.Data
+            ));
+        });
+
+        // missed events lost, so full state must be fetched again
+        eventSource.addEventListener('resync', () => {
+            dispatch(fetchOpcacheStatuses());
+            dispatch(fetchApcuStatuses());
+        });
+
+        return eventSource;
+    }
+}
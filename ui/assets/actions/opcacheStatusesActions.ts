@@ -1,6 +1,7 @@
 export const OPCACHE_STATUSES_FETCHED   = 'OPCACHE_STATUSES_FETCHED';
 export const CLUSTER_SWITCHED   = 'CLUSTER_SWITCHED';
 export const OPCACHE_STATUSES_REFRESHED   = 'OPCACHE_STATUSES_REFRESHED';
+export const NODE_STATUS_UPDATED   = 'NODE_STATUS_UPDATED';
 
 export const opcacheStatusesFetched = (opcacheStatuses: Object) => ({
     type: OPCACHE_STATUSES_FETCHED,
@@ -14,4 +15,12 @@ export const clusterSwitched = (clusterName: string) => ({
 
 export const opcacheStatusesRefreshed = () => ({
     type: OPCACHE_STATUSES_REFRESHED,
-});
\ No newline at end of file
+});
+
+export const nodeStatusUpdated = (clusterName: string, groupName: string, hostName: string, nodeUpdate: Object) => ({
+    type: NODE_STATUS_UPDATED,
+    clusterName,
+    groupName,
+    hostName,
+    nodeUpdate
+});
@@ -4,6 +4,27 @@ import AlertsPanel from '/components/AlertsPanel';
 const buildAlertsDataFromOpcacheStatus = function(opcacheStatus) {
     const alerts = [];
 
+    if (opcacheStatus.State === 'disabled') {
+        alerts.push({
+            'severity': 'warning',
+            'message': 'OPcache is disabled by "opcache.enable" on this node.',
+        });
+    }
+
+    if (opcacheStatus.State === 'empty') {
+        alerts.push({
+            'severity': 'info',
+            'message': 'No scripts cached yet, OPcache was reset or node just started.',
+        });
+    }
+
+    if (opcacheStatus.State === 'degraded' && !opcacheStatus.CacheFull) {
+        alerts.push({
+            'severity': 'warning',
+            'message': 'OPcache restart pending, new scripts are not cached until restart completes.',
+        });
+    }
+
     if (opcacheStatus.CacheFull) {
         alerts.push({
             'severity': 'error',
@@ -11,6 +32,14 @@ const buildAlertsDataFromOpcacheStatus = function(opcacheStatus) {
         });
     }
 
+    if (opcacheStatus.Jit && opcacheStatus.Jit.Enabled && opcacheStatus.Jit.BufferSize > 0
+        && opcacheStatus.Jit.BufferFree / opcacheStatus.Jit.BufferSize < 0.01) {
+        alerts.push({
+            'severity': 'warning',
+            'message': 'JIT buffer is exhausted, increase "opcache.jit_buffer_size".',
+        });
+    }
+
     return alerts;
 }
 
@@ -92,6 +92,8 @@ const buildTableData = function(clusterApcuStatuses) {
                         ].join(' '),
                     },
                 ],
+                fragmentation: buildFragmentationTableRows(clusterApcuStatuses[groupName][hostName].SmaInfo.Fragmentation),
+                cache: buildCacheTableRows(clusterApcuStatuses[groupName][hostName].CacheInfo),
             };
         }
     }
@@ -99,6 +101,45 @@ const buildTableData = function(clusterApcuStatuses) {
     return tables;
 };
 
+const buildFragmentationTableRows = function(fragmentation) {
+    // not reported by older agents
+    if (!fragmentation) {
+        return null;
+    }
+
+    const rows = [
+        {'label': 'Fragmentation', 'value': fragmentation.FragmentationPercentage.toFixed(2) + '%'},
+        {'label': 'Free blocks', 'value': fragmentation.FreeBlocksCount},
+        {'label': 'Largest free block', 'value': fragmentation.LargestFreeBlock + ' (' + prettyBytes(fragmentation.LargestFreeBlock) + ')'},
+    ];
+
+    for (let bucket of fragmentation.FreeBlocksHistogram) {
+        rows.push({
+            'label': bucket.MaxSize > 0 ? 'Free blocks up to ' + prettyBytes(bucket.MaxSize) : 'Larger free blocks',
+            'value': bucket.Count + ' (' + prettyBytes(bucket.Memory) + ')',
+        });
+    }
+
+    return rows;
+};
+
+const buildCacheTableRows = function(cacheInfo) {
+    // not reported by older agents
+    if (!cacheInfo) {
+        return null;
+    }
+
+    return [
+        {'label': 'Entries', 'value': cacheInfo.NumEntries},
+        {'label': 'Hits', 'value': cacheInfo.NumHits},
+        {'label': 'Misses', 'value': cacheInfo.NumMisses},
+        {'label': 'Hit ratio', 'value': (cacheInfo.HitRatio * 100).toFixed(2) + '%'},
+        {'label': 'Inserts', 'value': cacheInfo.NumInserts},
+        {'label': 'Expunges', 'value': cacheInfo.Expunges},
+        {'label': 'Used memory', 'value': cacheInfo.MemSize + ' (' + prettyBytes(cacheInfo.MemSize) + ')'},
+    ];
+};
+
 const buildChartData = function(clusterApcuStatuses) {
     const charts = {};
 
@@ -197,6 +238,8 @@ function StatusPageComponent(props: Object) {
             const hostChart = props.charts[groupName][hostName];
 
             let gridContent;
+            let fragmentationGridContent = null;
+            let cacheGridContent = null;
             if (hostChart !== null) {
                 gridContent = (
                     <Paper className={classes.paper} height="100%">
@@ -208,6 +251,28 @@ function StatusPageComponent(props: Object) {
                         <StatusTable rows={props.tables[groupName][hostName].memory}></StatusTable>
                     </Paper>
                 );
+
+                if (props.tables[groupName][hostName].fragmentation !== null) {
+                    fragmentationGridContent = (
+                        <Grid item xs={12} sm={6} md={4} key={hostName + "fragmentation"} height="100%">
+                            <Paper className={classes.paper} height="100%">
+                                <h2>Fragmentation</h2>
+                                <StatusTable rows={props.tables[groupName][hostName].fragmentation}></StatusTable>
+                            </Paper>
+                        </Grid>
+                    );
+                }
+
+                if (props.tables[groupName][hostName].cache !== null) {
+                    cacheGridContent = (
+                        <Grid item xs={12} sm={6} md={4} key={hostName + "cache"} height="100%">
+                            <Paper className={classes.paper} height="100%">
+                                <h2>Cache</h2>
+                                <StatusTable rows={props.tables[groupName][hostName].cache}></StatusTable>
+                            </Paper>
+                        </Grid>
+                    );
+                }
             } else {
                 gridContent = (
                     <Paper className={classes.paper} height="100%">APCu disabled</Paper>
@@ -222,6 +287,8 @@ function StatusPageComponent(props: Object) {
                         <Grid item xs={12} sm={6} md={4} key={hostName + "memory"} height="100%">
                             {gridContent}
                         </Grid>
+                        {fragmentationGridContent}
+                        {cacheGridContent}
                     </Grid>
                 </div>
             );
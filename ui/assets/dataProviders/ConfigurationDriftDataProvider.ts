@@ -0,0 +1,13 @@
+class ConfigurationDriftDataProvider {
+    async fetch(clusterName: string, groupName: string|null = null): Promise<Object> {
+        let url = '/api/clusters/' + encodeURIComponent(clusterName);
+
+        if (groupName) {
+            url += '/groups/' + encodeURIComponent(groupName);
+        }
+
+        return await fetch(url + '/configuration/drift').then(response => response.json());
+    }
+}
+
+export default ConfigurationDriftDataProvider;
@@ -0,0 +1,41 @@
+interface ScriptsQuery {
+    groupName?: string,
+    hostName?: string,
+    prefix?: string,
+    regex?: string,
+    sort?: 'memory'|'hits'|'age'|'lastUsed'|'path',
+    order?: 'asc'|'desc',
+    aggregate?: boolean,
+    limit?: number,
+    cursor?: string,
+}
+
+class ScriptsDataProvider {
+    async fetch(clusterName: string, query: ScriptsQuery = {}): Promise<Object> {
+        let url = '/api/clusters/' + encodeURIComponent(clusterName);
+
+        if (query.groupName) {
+            url += '/groups/' + encodeURIComponent(query.groupName);
+
+            if (query.hostName) {
+                url += '/nodes/' + encodeURIComponent(query.hostName);
+            }
+        }
+
+        const params = new URLSearchParams();
+
+        for (const param of ['prefix', 'regex', 'sort', 'order', 'limit', 'cursor']) {
+            if (query[param]) {
+                params.append(param, String(query[param]));
+            }
+        }
+
+        if (query.aggregate) {
+            params.append('aggregate', '1');
+        }
+
+        return await fetch(url + '/scripts?' + params.toString()).then(response => response.json());
+    }
+}
+
+export default ScriptsDataProvider;
@@ -0,0 +1,16 @@
+// Sends mutating request to API with CSRF token issued by server in cookie
+export default function postCommand(url: string): Promise<Response> {
+    const csrfTokenCookie = document.cookie
+        .split('; ')
+        .find((cookie: string) => cookie.startsWith('csrf_token='));
+
+    const csrfToken = csrfTokenCookie ? csrfTokenCookie.substring('csrf_token='.length) : '';
+
+    return fetch(url, {
+        method: 'POST',
+        credentials: 'same-origin',
+        headers: {
+            'X-CSRF-Token': csrfToken,
+        },
+    });
+}
@@ -9,6 +9,7 @@ import { ThemeProvider } from '@material-ui/core/styles';
 import Theme from '/components/Theme.tsx';
 import reducer from '/reducers/reducer';
 import fetchOpcacheStatuses from '/actionCreators/fetchOpcacheStatuses'
+import subscribeEvents from '/actionCreators/subscribeEvents'
 import {BrowserRouter as Router} from "react-router-dom";
 
 // store
@@ -35,3 +36,6 @@ ReactDOM.render(
 
 // start app
 store.dispatch(fetchOpcacheStatuses());
+
+// receive live updates of nodes
+store.dispatch(subscribeEvents());
@@ -1,6 +1,7 @@
 import {
     OPCACHE_STATUSES_FETCHED,
-    CLUSTER_SWITCHED
+    CLUSTER_SWITCHED,
+    NODE_STATUS_UPDATED
 } from '/actions/opcacheStatusesActions';
 import {APCU_STATUSES_FETCHED} from "../actions/apcuStatusesActions";
 
@@ -30,6 +31,29 @@ export default function(state = initialState, action: Object) {
                 apcuStatuses: action.apcuStatuses,
             }
 
+        case NODE_STATUS_UPDATED:
+            return {
+                ...state,
+                opcacheStatuses: mergeNodeStatus(
+                    state.opcacheStatuses,
+                    action.clusterName,
+                    action.groupName,
+                    action.hostName,
+                    {
+                        ...action.nodeUpdate.OpcacheStatistics,
+                        // scripts not sent in events, so keep previously fetched
+                        Scripts: getNodeStatus(state.opcacheStatuses, action.clusterName, action.groupName, action.hostName)['Scripts'],
+                    }
+                ),
+                apcuStatuses: mergeNodeStatus(
+                    state.apcuStatuses,
+                    action.clusterName,
+                    action.groupName,
+                    action.hostName,
+                    action.nodeUpdate.ApcuStatistics
+                ),
+            }
+
         case CLUSTER_SWITCHED: 
             return {
                 ...state,
@@ -43,3 +67,23 @@ export default function(state = initialState, action: Object) {
     return state;
 };
 
+
+
+function getNodeStatus(statuses: Object, clusterName: string, groupName: string, hostName: string): Object {
+    return (((statuses || {})[clusterName] || {})[groupName] || {})[hostName] || {};
+}
+
+function mergeNodeStatus(statuses: Object, clusterName: string, groupName: string, hostName: string, nodeStatus: Object): Object {
+    statuses = statuses || {};
+
+    return {
+        ...statuses,
+        [clusterName]: {
+            ...statuses[clusterName],
+            [groupName]: {
+                ...(statuses[clusterName] || {})[groupName],
+                [hostName]: nodeStatus,
+            },
+        },
+    };
+}